@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Palette is a color matrix that every frame is drawn through, so that
+// colors that look alike with a color vision deficiency are pulled apart.
+// The matrix of "normal" is nil, and the frame is drawn as it is.
+type Palette struct {
+	Name   string
+	Matrix *[3][3]float32 // the rows give the new red, green and blue
+}
+
+// palettes are the choices in the options menu. The others are daltonised
+// from the simulations of Machado et al. (2009): the colors that are lost
+// are shifted into the channels that are still seen.
+var palettes = []Palette{
+	{Name: "normal"},
+	daltonise("protanopia", [3][3]float32{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}, redGreenShift),
+	daltonise("deuteranopia", [3][3]float32{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}, redGreenShift),
+	daltonise("tritanopia", [3][3]float32{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}, blueYellowShift),
+}
+
+// redGreenShift moves the red that is lost into green and blue, and
+// blueYellowShift moves the blue into red and green
+var (
+	redGreenShift   = [3][3]float32{{0, 0, 0}, {0.7, 1, 0}, {0.7, 0, 1}}
+	blueYellowShift = [3][3]float32{{1, 0, 0.7}, {0, 1, 0.7}, {0, 0, 0}}
+)
+
+// daltonise returns the palette that adds the error of the simulation
+// back, shifted: I + shift × (I - simulation)
+func daltonise(name string, simulation, shift [3][3]float32) Palette {
+	var m [3][3]float32
+	for i := range 3 {
+		for j := range 3 {
+			if i == j {
+				m[i][j] = 1
+			}
+			for k := range 3 {
+				e := -simulation[k][j]
+				if k == j {
+					e++
+				}
+				m[i][j] += shift[i][k] * e
+			}
+		}
+	}
+	return Palette{Name: name, Matrix: &m}
+}
+
+// paletteNames are the names of the palettes, in the order of the menu
+func paletteNames() []string {
+	names := make([]string, len(palettes))
+	for i, p := range palettes {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// findPalette returns the palette with the given name
+func findPalette(name string) (*Palette, error) {
+	for i := range palettes {
+		if palettes[i].Name == name {
+			return &palettes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown palette %q", name)
+}
+
+// PALETTE_SHADER multiplies the colors with the matrix of a palette. The
+// colors are premultiplied, so they are kept below the alpha.
+const PALETTE_SHADER = `//kage:unit pixels
+package main
+
+var Red vec3
+var Green vec3
+var Blue vec3
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4) vec4 {
+	c := imageSrc0At(srcPos)
+	rgb := vec3(dot(Red, c.rgb), dot(Green, c.rgb), dot(Blue, c.rgb))
+	return vec4(clamp(rgb, vec3(0), vec3(c.a)), c.a)
+}
+`
+
+var (
+	paletteShader *ebiten.Shader
+	paletteScene  *ebiten.Image // the frame, before it goes through the palette
+)
+
+func loadPalette() error {
+	var err error
+	paletteShader, err = ebiten.NewShader([]byte(PALETTE_SHADER))
+	if err != nil {
+		return fmt.Errorf("palette shader: %w", err)
+	}
+	paletteScene = ebiten.NewImage(W, H)
+	return nil
+}
+
+// currentPalette returns the palette from the settings, or nil if the frame is
+// drawn as it is
+func currentPalette() *Palette {
+	p, err := findPalette(settings.Access.Palette)
+	if err != nil || p.Matrix == nil || paletteShader == nil {
+		return nil
+	}
+	return p
+}
+
+// applyPalette draws src to screen through the palette
+func applyPalette(screen, src *ebiten.Image, p *Palette) {
+	op := &ebiten.DrawRectShaderOptions{}
+	op.Images[0] = src
+	op.Uniforms = map[string]any{
+		"Red":   p.Matrix[0][:],
+		"Green": p.Matrix[1][:],
+		"Blue":  p.Matrix[2][:],
+	}
+	screen.DrawRectShader(W, H, paletteShader, op)
+}
+
+var (
+	outlineColor = color.White
+	outlineRim   = color.Black
+)
+
+// drawOutline draws a white square with a black rim behind an enemy
+// bullet, so that it stands out against anything
+func drawOutline(target *ebiten.Image, b *sim.Bullet) {
+	x, y := float32(b.X), float32(b.Y)
+	w, h := float32(images[ORB].Bounds().Dx()), float32(images[ORB].Bounds().Dy())
+	vector.FillRect(target, x-2, y-2, w+4, h+4, outlineRim, false)
+	vector.FillRect(target, x-1, y-1, w+2, h+2, outlineColor, false)
+}
+
+// AUTOFIRE is the number of ticks between shots while fire is held down,
+// with hold to fire on
+const AUTOFIRE = 8
+
+// fired returns true if a fire button that has been held down for d ticks
+// fires a bullet in this tick. Without hold to fire, it takes a press per bullet.
+func fired(d int) bool {
+	if settings.Access.HoldToFire {
+		return d > 0 && (d-1)%AUTOFIRE == 0
+	}
+	return d == 1
+}
+
+// MIN_SPEED is how far the game speed can be turned down in the options
+// menu, from 1, in steps of a tenth
+const MIN_SPEED = 0.5
+
+// gameTPS returns the ticks per second from -tps, slowed down by the game
+// speed. Slowing down the ticks instead of skipping some, like the time
+// control does, keeps the motion smooth. Online games run at full speed,
+// since both sides have to keep up with each other.
+func gameTPS() int {
+	if session != nil {
+		return *tpsFlag
+	}
+	return max(1, int(float64(*tpsFlag)*settings.Access.Speed+0.5))
+}
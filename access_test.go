@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestFired holds fire down for a while, with and without hold to fire
+func TestFired(t *testing.T) {
+	settings = defaultSettings()
+	for _, hold := range []bool{false, true} {
+		settings.Access.HoldToFire = hold
+		var shots []int
+		for d := range 3*AUTOFIRE + 1 {
+			if fired(d) {
+				shots = append(shots, d)
+			}
+		}
+		want := []int{1}
+		if hold {
+			want = []int{1, 1 + AUTOFIRE, 1 + 2*AUTOFIRE}
+		}
+		if !slices.Equal(shots, want) {
+			t.Errorf("hold to fire %v: fired after %v ticks, want %v", hold, shots, want)
+		}
+	}
+}
+
+func TestGameTPS(t *testing.T) {
+	settings = defaultSettings()
+	for _, tt := range []struct {
+		speed float64
+		want  int
+	}{
+		{1, *tpsFlag},
+		{0.7, 42},
+		{MIN_SPEED, 30},
+	} {
+		settings.Access.Speed = tt.speed
+		if tps := gameTPS(); tps != tt.want {
+			t.Errorf("at a speed of %g, the game runs at %d TPS, want %d", tt.speed, tps, tt.want)
+		}
+	}
+}
+
+// TestPalettes checks that the palettes leave grays alone, since the
+// simulations they are made from do
+func TestPalettes(t *testing.T) {
+	for _, name := range paletteNames() {
+		p, err := findPalette(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Matrix == nil {
+			continue
+		}
+		for i, row := range p.Matrix {
+			if sum := row[0] + row[1] + row[2]; math.Abs(float64(sum)-1) > 0.001 {
+				t.Errorf("%s: row %d turns white into %g", name, i, sum)
+			}
+		}
+	}
+	if _, err := findPalette("sepia"); err == nil {
+		t.Error("found a palette that does not exist")
+	}
+}
@@ -0,0 +1,99 @@
+// Package achievements keeps lifetime statistics from the events of the
+// game, and unlocks achievements when a statistic reaches a goal. The
+// achievements are defined in a file, one per line:
+//
+//	# id         stat           goal  name
+//	centurion    kills          100   Centurion: destroy 100 enemies
+//	untouchable  bosses-no-hit  1     Untouchable: defeat a boss without being hit
+//
+// The statistics are:
+//
+//	games          games played
+//	seconds        time played
+//	shots          bullets fired
+//	missiles       missiles fired
+//	hits           bullets and missiles that hit
+//	kills          enemies and bosses destroyed
+//	bosses         bosses defeated
+//	deaths         lives lost
+//	pickups        pickups collected
+//	bombs          bombs set off
+//	best-score     the highest score in one game
+//	waves-no-fire  waves that came and went without firing a shot
+//	bosses-no-hit  bosses defeated without being hit during the fight
+package achievements
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hi/textfile"
+)
+
+// Stats are the names of the statistics, in the order they are shown
+var Stats = []string{
+	"games", "seconds", "shots", "missiles", "hits", "kills", "bosses",
+	"deaths", "pickups", "bombs", "best-score", "waves-no-fire", "bosses-no-hit",
+}
+
+// Achievement is unlocked when a statistic reaches the goal
+type Achievement struct {
+	ID       string
+	Stat     string
+	Goal     int64
+	Name     string
+	Filename string
+	Line     int
+}
+
+// Load reads and parses an achievements file
+func Load(filename string) ([]*Achievement, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads achievements from r, in the order they are listed. The
+// filename is only used for error messages.
+func Parse(r io.Reader, filename string) ([]*Achievement, error) {
+	var list []*Achievement
+	errorf := func(line int, format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, errorf(line, "usage: <id> <stat> <goal> <name>")
+		}
+		a := &Achievement{ID: fields[0], Stat: fields[1], Name: strings.Join(fields[3:], " "), Filename: filename, Line: line}
+		for _, b := range list {
+			if b.ID == a.ID {
+				return nil, errorf(line, "%s is already defined on line %d", a.ID, b.Line)
+			}
+		}
+		if !slices.Contains(Stats, a.Stat) {
+			return nil, errorf(line, "unknown stat %q, expected one of %s", a.Stat, strings.Join(Stats, ", "))
+		}
+		var err error
+		if a.Goal, err = strconv.ParseInt(fields[2], 10, 64); err != nil || a.Goal < 1 {
+			return nil, errorf(line, "invalid goal %q, expected a number above 0", fields[2])
+		}
+		list = append(list, a)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
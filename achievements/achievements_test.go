@@ -0,0 +1,99 @@
+package achievements
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hi/sim"
+	"hi/textfile"
+)
+
+func TestParse(t *testing.T) {
+	src := `# id      stat    goal  name
+centurion kills   100   Centurion: destroy 100 enemies # a comment
+
+bomber    bombs   10    Bomber
+`
+	list, err := Parse(strings.NewReader(src), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Achievement{
+		{ID: "centurion", Stat: "kills", Goal: 100, Name: "Centurion: destroy 100 enemies", Filename: "test.txt", Line: 2},
+		{ID: "bomber", Stat: "bombs", Goal: 10, Name: "Bomber", Filename: "test.txt", Line: 4},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("parsed %d achievements, want %d", len(list), len(want))
+	}
+	for i, a := range list {
+		if *a != want[i] {
+			t.Errorf("achievement %d is %+v, want %+v", i+1, *a, want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"centurion kills 100", 1, "usage: <id> <stat> <goal> <name>"},
+		{"a kills 1 A\n\na bombs 1 A", 3, "a is already defined on line 1"},
+		{"# stat\na score 1 A", 2, `unknown stat "score", expected one of ` + strings.Join(Stats, ", ")},
+		{"a kills 0 A", 1, `invalid goal "0", expected a number above 0`},
+		{"a kills many A", 1, `invalid goal "many", expected a number above 0`},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.txt")
+		textfile.Check(t, err, "bad.txt", tt.line, tt.msg)
+	}
+}
+
+// TestTracker counts the events of a local and a remote player, and
+// unlocks an achievement once
+func TestTracker(t *testing.T) {
+	list, err := Parse(strings.NewReader("first-blood kills 1 First blood\nsurvivor deaths 2 Survivor"), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadProgress(filepath.Join(t.TempDir(), "progress.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := &Tracker{Progress: p, Achievements: list}
+	tracker.Local[0] = true
+	w := sim.NewWorld()
+	w.Step(nil)
+	for _, e := range []sim.Event{
+		sim.EnemyKilled{Player: 1, Kind: "grunt"},
+		sim.PlayerHit{Player: 0, Shield: true, Lives: 3},
+		sim.BulletFired{Player: 0, Bullets: 3},
+		sim.BulletFired{Player: 1, Bullets: 1},
+	} {
+		tracker.Handle(e)
+	}
+	if unlocked := tracker.Update(w); len(unlocked) != 0 {
+		t.Errorf("unlocked %v for the kill of another player", unlocked[0].ID)
+	}
+	tracker.Handle(sim.EnemyKilled{Player: 0, Kind: "grunt"})
+	w.Step(nil)
+	if unlocked := tracker.Update(w); len(unlocked) != 1 || unlocked[0].ID != "first-blood" {
+		t.Errorf("unlocked %v after the first kill", unlocked)
+	}
+	w.Step(nil)
+	if unlocked := tracker.Update(w); len(unlocked) != 0 {
+		t.Errorf("unlocked %v again", unlocked[0].ID)
+	}
+	s := p.Stats
+	if s["kills"] != 1 || s["deaths"] != 0 || s["shots"] != 3 || s["games"] != 1 {
+		t.Errorf("the stats are %v", s)
+	}
+}
+
+// TestLoad loads the achievements that ship with the game
+func TestLoad(t *testing.T) {
+	if _, err := Load("../data/achievements.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,173 @@
+package achievements
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"hi/level"
+	"hi/sim"
+)
+
+// Progress is what is kept between runs
+type Progress struct {
+	Stats    map[string]int64
+	Unlocked map[string]time.Time // when each achievement was unlocked, by ID
+}
+
+// LoadProgress reads the progress file, or returns no progress if there is none
+func LoadProgress(filename string) (*Progress, error) {
+	p := &Progress{Stats: make(map[string]int64), Unlocked: make(map[string]time.Time)}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return p, err
+	}
+	if p.Stats == nil {
+		p.Stats = make(map[string]int64)
+	}
+	if p.Unlocked == nil {
+		p.Unlocked = make(map[string]time.Time)
+	}
+	return p, nil
+}
+
+// Save writes the progress file
+func (p *Progress) Save(filename string) error {
+	data, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// Accuracy returns the fraction of shots and missiles that hit, from 0 to 1
+func (p *Progress) Accuracy() float64 {
+	fired := p.Stats["shots"] + p.Stats["missiles"]
+	if fired == 0 {
+		return 0
+	}
+	return float64(p.Stats["hits"]) / float64(fired)
+}
+
+// Tracker counts the statistics of the players at this computer. It gets
+// every event with Handle, and Update after every tick.
+type Tracker struct {
+	Progress     *Progress
+	Achievements []*Achievement
+	Local        [sim.MAX_PLAYERS]bool // the players whose stats are counted
+
+	playing bool   // a local player is in the game
+	tick    uint64 // the tick of the world at the last update
+	ticks   uint64 // ticks played, for the seconds
+	wave    bool   // there are enemies on the screen
+	fired   bool   // a local player fired during the wave
+	boss    bool   // a boss fight is going on
+	bossOK  bool   // no local player was hit during the boss fight
+}
+
+// Handle counts an event
+func (t *Tracker) Handle(e sim.Event) {
+	s := t.Progress.Stats
+	switch e := e.(type) {
+	case sim.BulletFired:
+		if !t.Local[e.Player] {
+			return
+		}
+		t.fired = true
+		if e.Missile {
+			s["missiles"]++
+		} else {
+			s["shots"] += int64(e.Bullets)
+		}
+	case sim.BulletHit:
+		if t.Local[e.Player] {
+			s["hits"]++
+		}
+	case sim.EnemyKilled:
+		if e.Player >= 0 && t.Local[e.Player] {
+			s["kills"]++
+		}
+		if e.Boss && t.boss {
+			s["bosses"]++
+			if t.bossOK {
+				s["bosses-no-hit"]++
+			}
+			t.boss = false
+		}
+	case sim.PlayerHit:
+		if !t.Local[e.Player] {
+			return
+		}
+		t.bossOK = false
+		if !e.Shield {
+			s["deaths"]++
+		}
+	case sim.PickupCollected:
+		if t.Local[e.Player] {
+			s["pickups"]++
+		}
+	case sim.BombUsed:
+		if t.Local[e.Player] {
+			s["bombs"]++
+		}
+	case sim.BossSpawned:
+		t.boss, t.bossOK = true, true
+	}
+}
+
+// Update counts what is counted per tick, and returns the achievements
+// that were unlocked. A game starts when a local player joins while none
+// is in the game, or when the tick goes back, since that is a new world.
+func (t *Tracker) Update(w *sim.World) []*Achievement {
+	s := t.Progress.Stats
+	playing := false
+	for i := range w.Players {
+		if t.Local[i] && w.Players[i].Joined {
+			playing = true
+			s["best-score"] = max(s["best-score"], int64(w.Players[i].Score))
+		}
+	}
+	if playing && (!t.playing || w.Tick <= t.tick) {
+		t.wave, t.boss = false, false
+		s["games"]++
+	}
+	t.tick = w.Tick
+	if t.playing = playing; !playing {
+		return nil
+	}
+	if t.ticks++; t.ticks%level.TPS == 0 {
+		s["seconds"]++
+	}
+
+	// a wave starts when enemies appear on an empty screen, and ends when they are all gone
+	switch {
+	case !t.wave && len(w.Enemies) > 0:
+		t.wave, t.fired = true, false
+	case t.wave && len(w.Enemies) == 0:
+		t.wave = false
+		if !t.fired {
+			s["waves-no-fire"]++
+		}
+	}
+
+	var unlocked []*Achievement
+	for _, a := range t.Achievements {
+		if _, ok := t.Progress.Unlocked[a.ID]; !ok && s[a.Stat] >= a.Goal {
+			t.Progress.Unlocked[a.ID] = time.Now()
+			unlocked = append(unlocked, a)
+		}
+	}
+	return unlocked
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+
+	"hi/bot"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+var attractFlag = flag.Bool("attract", true, "let the bot play a demo until someone presses join")
+
+// attract is the bot that plays the demo while nobody is playing, or nil
+var attract *bot.Bot
+
+// startAttract starts a new demo, with a new seed unless -seed was given
+func startAttract() error {
+	w, err := sim.LoadLevel(*levelFlag, pickSeed(), sim.DefaultDifficulty)
+	if err != nil {
+		return err
+	}
+	world, attract = w, bot.New(0)
+	return nil
+}
+
+// stepAttract steps the demo, starts over when it is done, and starts a
+// real game when someone presses join
+func stepAttract() error {
+	if !console.Open && joinPressed() {
+		w, err := sim.LoadLevel(*levelFlag, pickSeed(), gameDifficulty())
+		if err != nil {
+			return err
+		}
+		world, attract, seats, latch = w, nil, NewSeats(), &sim.Latch{}
+		return nil
+	}
+	world.Step([]sim.Input{attract.Input(world)})
+	if world.GameOver() || (world.Level.Done() && len(world.Enemies) == 0 && world.Boss == nil) {
+		return startAttract()
+	}
+	return nil
+}
+
+// joinPressed returns true if the join key or button of any device was just pressed
+func joinPressed() bool {
+	if inpututil.IsKeyJustPressed(keyboard1.Join) || inpututil.IsKeyJustPressed(keyboard2.Join) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+			return true
+		}
+	}
+	return false
+}
+
+// drawAttract draws the title over the demo
+func drawAttract(screen *ebiten.Image) {
+	text := msg("attract.demo")
+	ebitenutil.DebugPrintAt(screen, text, W/2-textWidth(text)/2, 16)
+	// blinks twice a second
+	if (world.Tick/30)%2 == 0 {
+		text := msg("attract.press", keyboard1.Join.String())
+		ebitenutil.DebugPrintAt(screen, text, W/2-textWidth(text)/2, H/2-8)
+	}
+}
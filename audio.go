@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"hi/level"
+	"hi/mixer"
+	"hi/sfx"
+	"hi/sim"
+)
+
+var audioLogFlag = flag.String("audio-log", "", "write what the mixer plays to this file")
+
+// mix is the mixer. There is no audio output yet, so it plays through the
+// null backend, which can log what it would have played.
+var mix = mixer.NewNull(nil, level.TPS)
+
+// audioLog is the -audio-log file
+var audioLog *os.File
+
+// mixes say how the sounds from the sounds file are mixed
+var mixes = map[string]mixer.Sound{
+	"shoot":     {Category: mixer.SFX, Volume: 0.6, MaxVoices: 2, Jitter: 0.05},
+	"missile":   {Category: mixer.SFX, Volume: 0.7, MaxVoices: 2, Jitter: 0.05},
+	"hit":       {Category: mixer.SFX, Volume: 0.6, MaxVoices: 3, Jitter: 0.1},
+	"explosion": {Category: mixer.SFX, Volume: 1, MaxVoices: 4, Jitter: 0.1, Duck: 0.3},
+	"hurt":      {Category: mixer.SFX, Volume: 1, MaxVoices: 1, Duck: 0.5},
+	"pickup":    {Category: mixer.SFX, Volume: 0.8, MaxVoices: 2, Jitter: 0.02},
+	"bomb":      {Category: mixer.SFX, Volume: 1, MaxVoices: 1, Duck: 0.7},
+	"boss":      {Category: mixer.SFX, Volume: 1, MaxVoices: 1, Duck: 0.5},
+	"menu":      {Category: mixer.UI, Volume: 0.6, MaxVoices: 1},
+}
+
+// music is how the music is mixed. It plays from the start, and the sounds
+// that duck turn it down.
+var music = mixer.Sound{Name: "music", Category: mixer.Music, Volume: 0.7, MaxVoices: 1, Loop: true}
+
+// loadAudio synthesises the sounds and the music, adds them to the mixer
+// and starts the music
+func loadAudio() error {
+	sounds, err := sfx.Load(SOUNDS)
+	if err != nil {
+		return err
+	}
+	song, err := sfx.LoadSong(MUSIC)
+	if err != nil {
+		return err
+	}
+	if *audioLogFlag != "" {
+		if audioLog, err = os.Create(*audioLogFlag); err != nil {
+			return err
+		}
+		mix = mixer.NewNull(audioLog, level.TPS)
+	}
+	// headless, the mixer is updated once per tick, and with a window Update
+	// keeps this up with the game speed
+	mix.TPS = *tpsFlag
+	for name, s := range sounds {
+		// sounds without a mix are effects that play up to four at a time
+		m, ok := mixes[name]
+		if !ok {
+			m = mixer.Sound{Category: mixer.SFX, Volume: 1, MaxVoices: 4}
+		}
+		m.Name, m.Samples = name, s.Generate()
+		mix.Add(&m)
+	}
+	music.Samples = song.Generate()
+	mix.Add(&music)
+	settings.applyAudio()
+	mix.Play(music.Name)
+	return nil
+}
+
+// closeAudio stops the sounds and closes the -audio-log file
+func closeAudio() error {
+	mix.StopAll()
+	if audioLog == nil {
+		return nil
+	}
+	err := mix.Backend.(*mixer.Null).Err
+	if closeErr := audioLog.Close(); err == nil {
+		err = closeErr
+	}
+	audioLog = nil
+	return err
+}
+
+func init() {
+	sim.On(bus, func(e sim.BulletFired) {
+		if e.Missile {
+			mix.Play("missile")
+		} else {
+			mix.Play("shoot")
+		}
+	})
+	sim.On(bus, func(sim.BulletHit) { mix.Play("hit") })
+	sim.On(bus, func(sim.EnemyKilled) { mix.Play("explosion") })
+	sim.On(bus, func(sim.PlayerHit) { mix.Play("hurt") })
+	sim.On(bus, func(sim.BombUsed) { mix.Play("bomb") })
+	sim.On(bus, func(sim.PickupCollected) { mix.Play("pickup") })
+	sim.On(bus, func(sim.BossSpawned) { mix.Play("boss") })
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"hi/mixer"
+	"hi/sim"
+)
+
+// TestMusic checks that the music plays once the audio is loaded, and that
+// it is turned down under an explosion
+func TestMusic(t *testing.T) {
+	settings = defaultSettings()
+	if err := loadAudio(); err != nil {
+		t.Fatal(err)
+	}
+	defer closeAudio()
+	voices := mix.Voices()
+	if len(voices) != 1 || voices[0].Sound.Name != "music" || voices[0].Sound.Category != mixer.Music {
+		t.Fatalf("the voices are %v, want the music", voices)
+	}
+	music := voices[0]
+	mix.Update()
+	loud := music.Volume
+	bus.Publish([]sim.Event{sim.EnemyKilled{Kind: "grunt"}})
+	mix.Update()
+	if music.Volume >= loud {
+		t.Errorf("the music is at %g under an explosion, and %g without", music.Volume, loud)
+	}
+}
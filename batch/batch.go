@@ -0,0 +1,221 @@
+// Package batch runs many games without a window, with pilots instead of
+// players, and collects statistics for comparing how hard levels are.
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"hi/bot"
+	"hi/level"
+	"hi/sim"
+)
+
+// Pilot decides the input of one player, every tick
+type Pilot interface {
+	Input(w *sim.World) sim.Input
+}
+
+// Pilots make pilots by name, for the given player and seed
+var Pilots = map[string]func(player int, seed uint64) Pilot{
+	"idle":   func(int, uint64) Pilot { return idle{} },
+	"random": newRandom,
+	"bot":    func(player int, _ uint64) Pilot { return bot.New(player) },
+}
+
+// PilotNames returns the names of the pilots, sorted
+func PilotNames() []string {
+	names := make([]string, 0, len(Pilots))
+	for name := range Pilots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config says which games to run
+type Config struct {
+	Level      *level.Level
+	Difficulty sim.Difficulty // the default difficulty if it is not set
+	Seeds      []uint64
+	Ticks      uint64 // the longest a game may go on
+	Players    int
+	Pilot      string
+	Workers    int // games that run at the same time, or the number of CPUs if 0
+}
+
+// Result is how one player did in one game
+type Result struct {
+	Seed     uint64  `json:"seed"`
+	Player   int     `json:"player"`
+	Ticks    uint64  `json:"ticks"`    // how long the game went on
+	Survived float64 `json:"survived"` // seconds in the game
+	Score    int     `json:"score"`
+	Damage   int     `json:"damage"`
+	Lives    int     `json:"lives"` // lives left at the end
+	Fired    int     `json:"fired"`
+	Hits     int     `json:"hits"`
+	Accuracy float64 `json:"accuracy"` // hits per bullet fired
+	Kills    int     `json:"kills"`
+	Cleared  bool    `json:"cleared"` // the level and its boss were done before the game was over
+}
+
+// Run plays a game for every seed, spread over the workers, and returns
+// the results in the order of the seeds
+func Run(c Config) ([]Result, error) {
+	if Pilots[c.Pilot] == nil {
+		return nil, fmt.Errorf("unknown pilot %q, the pilots are %v", c.Pilot, PilotNames())
+	}
+	if c.Players < 1 || c.Players > sim.MAX_PLAYERS {
+		return nil, fmt.Errorf("the number of players must be from 1 to %d, not %d", sim.MAX_PLAYERS, c.Players)
+	}
+	if c.Difficulty == (sim.Difficulty{}) {
+		c.Difficulty = sim.DefaultDifficulty
+	}
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	games := make([][]Result, len(c.Seeds))
+	errs := make([]error, len(c.Seeds))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				games[i], errs[i] = c.play(c.Seeds[i])
+			}
+		}()
+	}
+	for i := range c.Seeds {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	// the games fail in the same way, since they play the same level
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	var results []Result
+	for _, g := range games {
+		results = append(results, g...)
+	}
+	return results, nil
+}
+
+// play plays one game to the end and returns how every player did
+func (c *Config) play(seed uint64) ([]Result, error) {
+	w := sim.NewWorld()
+	w.SetSeed(seed)
+	w.SetDifficulty(c.Difficulty)
+	if err := w.Load(c.Level); err != nil {
+		return nil, err
+	}
+	pilots := make([]Pilot, c.Players)
+	inputs := make([]sim.Input, c.Players)
+	for i := range pilots {
+		pilots[i] = Pilots[c.Pilot](i, seed+uint64(i))
+		inputs[i] = sim.Join
+	}
+	w.Step(inputs)
+	cleared := false
+	for w.Tick < c.Ticks && !w.GameOver() {
+		if w.Level.Done() && len(w.Enemies) == 0 && w.Boss == nil {
+			cleared = true
+			break
+		}
+		for i, p := range pilots {
+			inputs[i] = p.Input(w)
+		}
+		w.Step(inputs)
+	}
+	results := make([]Result, c.Players)
+	for i := range results {
+		p := &w.Players[i]
+		r := Result{
+			Seed:     seed,
+			Player:   i + 1,
+			Ticks:    w.Tick,
+			Survived: float64(p.Stats.Alive) / level.TPS,
+			Score:    p.Score,
+			Damage:   p.Stats.Damage,
+			Lives:    p.Lives,
+			Fired:    p.Stats.Fired,
+			Hits:     p.Stats.Hits,
+			Kills:    p.Stats.Kills,
+			Cleared:  cleared,
+		}
+		if r.Fired > 0 {
+			r.Accuracy = float64(r.Hits) / float64(r.Fired)
+		}
+		results[i] = r
+	}
+	return results, nil
+}
+
+// WriteCSV writes the results with a header line
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"seed", "player", "ticks", "survived", "score", "damage", "lives", "fired", "hits", "accuracy", "kills", "cleared"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		err := cw.Write([]string{
+			strconv.FormatUint(r.Seed, 10),
+			strconv.Itoa(r.Player),
+			strconv.FormatUint(r.Ticks, 10),
+			strconv.FormatFloat(r.Survived, 'f', 2, 64),
+			strconv.Itoa(r.Score),
+			strconv.Itoa(r.Damage),
+			strconv.Itoa(r.Lives),
+			strconv.Itoa(r.Fired),
+			strconv.Itoa(r.Hits),
+			strconv.FormatFloat(r.Accuracy, 'f', 3, 64),
+			strconv.Itoa(r.Kills),
+			strconv.FormatBool(r.Cleared),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the results as a JSON array
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// Summary returns the averages of the results, in one line
+func Summary(results []Result) string {
+	if len(results) == 0 {
+		return "no games"
+	}
+	var survived, score, damage, accuracy float64
+	cleared := 0
+	for _, r := range results {
+		survived += r.Survived
+		score += float64(r.Score)
+		damage += float64(r.Damage)
+		accuracy += r.Accuracy
+		if r.Cleared {
+			cleared++
+		}
+	}
+	n := float64(len(results))
+	return fmt.Sprintf("%d results: survived %.1fs, score %.0f, damage %.2f, accuracy %.1f%%, cleared %.0f%% on average",
+		len(results), survived/n, score/n, damage/n, 100*accuracy/n, 100*float64(cleared)/n)
+}
@@ -0,0 +1,75 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/level"
+	"hi/sim"
+)
+
+// TestRun checks that the same seeds play out the same way, however many
+// workers play them, and that the results come in the order of the seeds
+func TestRun(t *testing.T) {
+	if err := sim.LoadData(".."); err != nil {
+		t.Fatal(err)
+	}
+	l, err := level.Load("../levels/1.lvl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Config{Level: l, Seeds: []uint64{4, 1, 3, 2}, Ticks: 30 * level.TPS, Players: 2, Pilot: "random", Workers: 1}
+	one, err := Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Workers = 4
+	four, err := Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(one, four) || Summary(one) != Summary(four) {
+		t.Errorf("one worker got\n%s\nand four got\n%s", Summary(one), Summary(four))
+	}
+	var seeds []uint64
+	for i, r := range four {
+		seeds = append(seeds, r.Seed)
+		if r.Player != i%2+1 {
+			t.Errorf("result %d is for player %d", i, r.Player)
+		}
+	}
+	if want := []uint64{4, 4, 1, 1, 3, 3, 2, 2}; !slices.Equal(seeds, want) {
+		t.Errorf("the results are for the seeds %v, want %v", seeds, want)
+	}
+	if a, b := four[0], four[2]; a.Score == b.Score && a.Fired == b.Fired && a.Survived == b.Survived {
+		t.Error("two seeds played out the same way")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, four); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(four)+1 || records[0][0] != "seed" || records[1][0] != "4" || len(records[1]) != len(records[0]) {
+		t.Errorf("the CSV is %v", records)
+	}
+}
+
+// TestRunErrors checks that a level that does not load fails the run,
+// instead of every game being played without it
+func TestRunErrors(t *testing.T) {
+	l, err := level.Parse(strings.NewReader("2s spawn nobody 160 -16\n"), "bad.lvl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Run(Config{Level: l, Seeds: []uint64{1, 2, 3}, Ticks: 60, Players: 1, Pilot: "idle"})
+	if err == nil || err.Error() != `bad.lvl:1: unknown enemy "nobody"` {
+		t.Errorf("got %d results and %v", len(results), err)
+	}
+}
@@ -0,0 +1,45 @@
+package batch
+
+import (
+	"hi/sim"
+)
+
+// idle never presses anything, for seeing how long the level takes to kill a player
+type idle struct{}
+
+func (idle) Input(*sim.World) sim.Input {
+	return 0
+}
+
+// random holds a random direction for a random number of ticks, and fires
+// every few ticks, like someone mashing the keys
+type random struct {
+	rand sim.Rand
+	in   sim.Input
+	hold int
+	fire int
+}
+
+func newRandom(_ int, seed uint64) Pilot {
+	return &random{rand: sim.Rand{State: seed}}
+}
+
+func (r *random) Input(*sim.World) sim.Input {
+	if r.hold <= 0 {
+		r.in = sim.Input(r.rand.Intn(16)) // any of left, right, up and down
+		if r.rand.Intn(4) == 0 {
+			r.in |= sim.Focus
+		}
+		r.hold = 10 + r.rand.Intn(50)
+	}
+	r.hold--
+	in := r.in | sim.Missile
+	if r.fire--; r.fire <= 0 {
+		in |= sim.Fire
+		r.fire = 4 + r.rand.Intn(8)
+	}
+	if r.rand.Intn(1200) == 0 {
+		in |= sim.Bomb
+	}
+	return in
+}
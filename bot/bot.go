@@ -0,0 +1,176 @@
+// Package bot is an autopilot that plays the game through the same inputs
+// as a player. Every tick it tries each direction, with and without focus,
+// a short while into the future, and picks the one that keeps the ship away
+// from enemy bullets while lining up with enemies and picking up pickups.
+//
+// The future is guessed by moving the ship with the real physics, and the
+// bullets in a straight line, so the bot is deterministic and cheap enough
+// to run in the balance runner.
+package bot
+
+import (
+	"math"
+
+	"hi/sim"
+)
+
+const (
+	HORIZON    = 16 // ticks to look ahead
+	SAFETY     = 3  // extra pixels around the hitbox that count as being hit
+	NEAR       = 16 // bullets closer than this are uncomfortable
+	SIGHT      = 96 // bullets further away than this are ignored
+	FIRE_EVERY = 6  // ticks between shots
+	PANIC      = 4  // bomb when every move gets hit within this many ticks
+	HOME_Y     = sim.H - 48
+	RANGE      = sim.BULLET_LIFE * 3 / 4 // how far below a target to stay, since bullets only go so far
+)
+
+// Bot plays one player
+type Bot struct {
+	Player int
+	fire   int
+}
+
+// New returns a bot for the given player
+func New(player int) *Bot {
+	return &Bot{Player: player}
+}
+
+type move struct {
+	dx, dy int
+	focus  bool
+}
+
+var moves = func() []move {
+	var ms []move
+	for _, focus := range []bool{false, true} {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				ms = append(ms, move{dx, dy, focus})
+			}
+		}
+	}
+	return ms
+}()
+
+// Input returns the input for the next tick
+func (b *Bot) Input(w *sim.World) sim.Input {
+	p := &w.Players[b.Player]
+	if !p.Joined {
+		return 0
+	}
+	best, bestScore, bestHit := moves[0], math.Inf(-1), 0
+	for _, m := range moves {
+		score, hit := b.try(w, p, m)
+		if score > bestScore {
+			best, bestScore, bestHit = m, score, hit
+		}
+	}
+
+	in := sim.Missile
+	switch best.dx {
+	case -1:
+		in |= sim.Left
+	case 1:
+		in |= sim.Right
+	}
+	switch best.dy {
+	case -1:
+		in |= sim.Up
+	case 1:
+		in |= sim.Down
+	}
+	if best.focus {
+		in |= sim.Focus
+	}
+	if b.fire--; b.fire <= 0 {
+		in |= sim.Fire
+		b.fire = FIRE_EVERY
+	}
+	if bestHit > 0 && bestHit <= PANIC && p.Bombs > 0 && p.Invuln == 0 && p.ShieldTime == 0 {
+		in |= sim.Bomb
+	}
+	return in
+}
+
+// try scores a move by following it for HORIZON ticks. It also returns the
+// tick the ship would first be hit at, or 0 if it would not be hit.
+func (b *Bot) try(w *sim.World, p *sim.Player, m move) (float64, int) {
+	pos, vel := p.Ship, p.Vel
+	danger, hit := 0.0, 0
+	for t := 1; t <= HORIZON; t++ {
+		pos, vel = w.Physics.Move(pos, vel, m.dx, m.dy, m.focus, p.SpeedTime > 0)
+		cx, cy := pos.X+sim.SHIP_W/2, pos.Y+sim.SHIP_H/2
+		// sooner is worse, since there is less time to get out of the way
+		weight := float64(HORIZON - t + 1)
+		for i := range w.Bullets {
+			bl := &w.Bullets[i]
+			if !bl.Hostile || math.Abs(bl.X-cx) > SIGHT || math.Abs(bl.Y-cy) > SIGHT {
+				continue
+			}
+			bx := bl.X + bl.VX*float64(t) + sim.ENEMY_BULLET_W/2
+			by := bl.Y + bl.VY*float64(t) + sim.ENEMY_BULLET_H/2
+			d := math.Hypot(bx-cx, by-cy)
+			switch {
+			case d < (sim.HITBOX+sim.ENEMY_BULLET_W)/2+SAFETY:
+				danger += 100 * weight
+				if hit == 0 {
+					hit = t
+				}
+			case d < NEAR:
+				danger += weight * (NEAR - d) / NEAR
+			}
+		}
+		for i := range w.Enemies {
+			e := &w.Enemies[i]
+			ex, ey := e.X+sim.ENEMY_W/2, e.Y+sim.ENEMY_H/2+e.Kind.Speed*float64(t)
+			if math.Abs(ex-cx) < sim.ENEMY_W/2+SAFETY && math.Abs(ey-cy) < sim.ENEMY_H/2+SAFETY {
+				danger += 100 * weight
+				if hit == 0 {
+					hit = t
+				}
+			}
+		}
+	}
+	if p.Invuln > HORIZON {
+		danger /= 10
+	}
+	return b.goal(w, pos) - danger, hit
+}
+
+// goal scores where the ship ends up: below a target and within range of
+// it, near pickups, and otherwise close to the bottom of the screen
+func (b *Bot) goal(w *sim.World, pos sim.Vec2) float64 {
+	cx, cy := pos.X+sim.SHIP_W/2, pos.Y+sim.SHIP_H/2
+	home := float64(HOME_Y)
+	score := 0.0
+	if x, y, ok := target(w, cy); ok {
+		score -= math.Abs(cx-x) * 0.15
+		home = math.Min(home, y+RANGE)
+	}
+	score -= math.Abs(cy-home) * 0.1
+	for _, pk := range w.Pickups {
+		if d := math.Hypot(pk.X+sim.PICKUP_W/2-cx, pk.Y+sim.PICKUP_H/2-cy); d < 96 {
+			score -= d * 0.08
+		}
+	}
+	return score
+}
+
+// target returns the bottom middle of what to shoot at: the boss, or the
+// lowest enemy on the screen that is above the ship
+func target(w *sim.World, y float64) (float64, float64, bool) {
+	if b := w.Boss; b != nil {
+		return b.X + b.Kind.W/2, b.Y + b.Kind.H, true
+	}
+	x, lowest := 0.0, -1.0
+	for _, e := range w.Enemies {
+		if e.HP <= 0 || e.Y < 0 || e.Y+sim.ENEMY_H > y || e.X < 0 || e.X > sim.W-sim.ENEMY_W {
+			continue
+		}
+		if e.Y > lowest {
+			x, lowest = e.X+sim.ENEMY_W/2, e.Y
+		}
+	}
+	return x, lowest + sim.ENEMY_H, lowest >= 0
+}
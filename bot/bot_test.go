@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"testing"
+
+	"hi/curve"
+	"hi/sim"
+)
+
+// play steps the world with the bot for the first player, and returns how
+// often it was hit
+func play(w *sim.World, b *Bot, ticks int) int {
+	hits := 0
+	for range ticks {
+		w.Step([]sim.Input{b.Input(w)})
+		for _, e := range w.Events {
+			if _, ok := e.(sim.PlayerHit); ok {
+				hits++
+			}
+		}
+	}
+	return hits
+}
+
+// TestDodge fires a wall of bullets with a gap at the ship, which the bot
+// has to get through
+func TestDodge(t *testing.T) {
+	wall := func() *sim.World {
+		w := sim.NewWorld()
+		p := &w.Players[0]
+		p.Invuln = 0
+		cx := p.Ship.X + sim.SHIP_W/2
+		for x := 0.0; x < sim.W; x += 8 {
+			if x > cx+24 && x < cx+48 {
+				continue
+			}
+			w.Bullets = append(w.Bullets, sim.Bullet{X: x, Y: p.Ship.Y - 40, VY: 1.5, Life: 200, Hostile: true})
+		}
+		return w
+	}
+	idle := wall()
+	for range 100 {
+		idle.Step(nil)
+	}
+	if idle.Players[0].Lives == sim.LIVES {
+		t.Fatal("the wall does not hit a ship that stays where it is")
+	}
+	if hits := play(wall(), New(0), 100); hits > 0 {
+		t.Errorf("the bot was hit %d times", hits)
+	}
+}
+
+// TestAim checks that the bot lines up below an enemy, and fires at it
+func TestAim(t *testing.T) {
+	w := sim.NewWorld()
+	p := &w.Players[0]
+	target := &sim.Kind{Name: "target", HP: 1000}
+	w.Enemies = append(w.Enemies, sim.Enemy{Kind: target, X: sim.W - 40, Y: 40, HP: 1000, Start: curve.Point{X: sim.W - 40, Y: 40}})
+	b := New(0)
+	fired := 0
+	for range 120 {
+		in := b.Input(w)
+		if in.Has(sim.Fire) {
+			fired++
+		}
+		w.Step([]sim.Input{in})
+	}
+	if d := p.Ship.X + sim.SHIP_W/2 - (sim.W - 40 + sim.ENEMY_W/2); d < -8 || d > 8 {
+		t.Errorf("the ship is %g pixels to the side of the enemy", d)
+	}
+	if fired != 120/FIRE_EVERY {
+		t.Errorf("the bot fired %d times in 120 ticks, want %d", fired, 120/FIRE_EVERY)
+	}
+	if p.Stats.Hits == 0 {
+		t.Error("the bot did not hit the enemy")
+	}
+}
+
+// TestDeterministic checks that bots make the same moves in the same game
+func TestDeterministic(t *testing.T) {
+	worlds := [2]*sim.World{sim.NewWorld(), sim.NewWorld()}
+	for _, w := range worlds {
+		w.SetSeed(3)
+		for i := range 40 {
+			w.Bullets = append(w.Bullets, sim.Bullet{X: float64(i * 8), Y: float64(i % 5 * 10), VX: float64(i%3) - 1, VY: 1, Life: 300, Hostile: true})
+		}
+	}
+	a, b := New(0), New(0)
+	for range 200 {
+		worlds[0].Step([]sim.Input{a.Input(worlds[0])})
+		worlds[1].Step([]sim.Input{b.Input(worlds[1])})
+	}
+	if worlds[0].Hash() != worlds[1].Hash() {
+		t.Error("two bots played the same game differently")
+	}
+}
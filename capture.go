@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"hi/replay"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	CAPTURES = "captures" // where screenshots and GIFs are saved
+
+	GIF_SECONDS = 5 // how much the GIF recording keeps
+	GIF_EVERY   = 2 // ticks between GIF frames, so 30 frames per second
+)
+
+// Capture saves screenshots, and keeps the last few seconds of frames in a
+// ring buffer for saving as an animated GIF. The files are written on
+// background goroutines, so that the game does not stall.
+type Capture struct {
+	Dir       string
+	Recording bool // frames are being kept for a GIF
+
+	shot   bool          // take a screenshot at the end of the next frame
+	frames []*image.RGBA // ring buffer of GIF frames
+	next   int           // the oldest frame in the ring, once it is full
+	ticks  uint64
+	saving sync.WaitGroup
+}
+
+var capture = &Capture{Dir: CAPTURES}
+
+// Screenshot takes a screenshot of the next frame that is drawn
+func (c *Capture) Screenshot() {
+	c.shot = true
+}
+
+// ToggleGIF starts keeping frames, or saves the frames that were kept as a GIF
+func (c *Capture) ToggleGIF() {
+	if c.Recording && len(c.frames) > 0 {
+		frames := append(c.frames[c.next:len(c.frames):len(c.frames)], c.frames[:c.next]...)
+		c.background(func(filename string) error { return saveGIF(filename, frames) }, ".gif")
+	}
+	c.Recording = !c.Recording
+	c.frames, c.next, c.ticks = nil, 0, 0
+}
+
+// Frame is called with the final frame, after everything has been drawn
+func (c *Capture) Frame(screen *ebiten.Image) {
+	if c.shot {
+		c.shot = false
+		img := readFrame(screen)
+		c.background(func(filename string) error { return savePNG(filename, img) }, ".png")
+	}
+	if !c.Recording {
+		return
+	}
+	c.ticks++
+	if c.ticks%GIF_EVERY != 0 {
+		return
+	}
+	if len(c.frames) < GIF_SECONDS*ebiten.TPS()/GIF_EVERY {
+		c.frames = append(c.frames, readFrame(screen))
+		return
+	}
+	// reuse the oldest frame
+	screen.ReadPixels(c.frames[c.next].Pix)
+	c.next = (c.next + 1) % len(c.frames)
+}
+
+// Wait waits for the files that are being written, before the game quits
+func (c *Capture) Wait() {
+	c.saving.Wait()
+}
+
+// background runs save with a new filename in the capture directory, with the current time in it
+func (c *Capture) background(save func(filename string) error, ext string) {
+	filename := filepath.Join(c.Dir, "hi-"+time.Now().Format("20060102-150405.000")+ext)
+	c.saving.Add(1)
+	go func() {
+		defer c.saving.Done()
+		err := os.MkdirAll(c.Dir, 0o755)
+		if err == nil {
+			err = save(filename)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		fmt.Println("saved", filename)
+	}()
+}
+
+func readFrame(screen *ebiten.Image) *image.RGBA {
+	img := image.NewRGBA(screen.Bounds())
+	screen.ReadPixels(img.Pix)
+	return img
+}
+
+// saveGIF writes the frames as an animated GIF. The colors are rounded to
+// the web safe palette, which is fast and good enough for the few colors
+// of the game.
+func saveGIF(filename string, frames []*image.RGBA) error {
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		b := frame.Bounds()
+		p := image.NewPaletted(b, palette.WebSafe)
+		for i, j := 0, 0; i < len(frame.Pix); i, j = i+4, j+1 {
+			r, g, b := (int(frame.Pix[i])+25)/51, (int(frame.Pix[i+1])+25)/51, (int(frame.Pix[i+2])+25)/51
+			p.Pix[j] = uint8(r*36 + g*6 + b)
+		}
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, 100*GIF_EVERY/ebiten.TPS())
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// savePNG writes an image as a PNG file
+func savePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Renderer draws every frame of a replay offscreen, and saves them as
+// numbered PNG files that can be made into a video
+type Renderer struct {
+	Replay *replay.Replay
+	Dir    string
+	Every  int // draw every nth tick
+	frames int
+}
+
+// replayRenderCommand renders a replay to PNG files. Like the render test,
+// it needs a window for the GPU.
+func replayRenderCommand(args []string) error {
+	fs := flag.NewFlagSet("hi replay render", flag.ContinueOnError)
+	r := &Renderer{}
+	fs.StringVar(&r.Dir, "o", "frames", "the directory to write the frames to")
+	fs.IntVar(&r.Every, "every", 1, "draw every nth tick, 2 makes 30 frames per second")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi replay render [flags] <file>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("give one replay file")
+	}
+	if r.Every < 1 {
+		return fmt.Errorf("-every must be at least 1, not %d", r.Every)
+	}
+	if err := loadData(); err != nil {
+		return err
+	}
+	var err error
+	if r.Replay, err = replay.Load(fs.Arg(0)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return err
+	}
+	ebiten.SetWindowTitle("hi replay render")
+	ebiten.SetWindowSize(W, H)
+	if err := ebiten.RunGame(r); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d frames\n", r.Dir, r.frames)
+	return nil
+}
+
+// Update plays the whole replay on the first tick, and then quits. The
+// frames are encoded in the background, a few at a time.
+func (r *Renderer) Update() error {
+	settings = defaultSettings()
+	if err := loadResources(); err != nil {
+		return err
+	}
+	w, err := sim.LoadLevel(r.Replay.Level, r.Replay.Seed, r.Replay.Difficulty)
+	if err != nil {
+		return err
+	}
+	world, debug, attract, shake = w, false, nil, 0
+	player := &replay.Player{Replay: r.Replay}
+
+	var (
+		saving sync.WaitGroup
+		errs   = make(chan error, 1)
+		slots  = make(chan struct{}, runtime.NumCPU())
+	)
+	frame := ebiten.NewImage(W, H)
+	for {
+		more, err := player.Step(world)
+		if err != nil || !more {
+			saving.Wait()
+			select {
+			case saveErr := <-errs:
+				return saveErr
+			default:
+			}
+			if err != nil {
+				return err
+			}
+			return ebiten.Termination
+		}
+		if world.Tick%uint64(r.Every) != 0 {
+			continue
+		}
+		frame.Clear()
+		(&Game{}).Draw(frame)
+		img := readFrame(frame)
+		filename := filepath.Join(r.Dir, fmt.Sprintf("%06d.png", r.frames))
+		r.frames++
+		slots <- struct{}{}
+		saving.Add(1)
+		go func() {
+			defer func() { <-slots; saving.Done() }()
+			if err := savePNG(filename, img); err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}()
+	}
+}
+
+func (r *Renderer) Draw(screen *ebiten.Image) {}
+
+func (r *Renderer) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return W, H
+}
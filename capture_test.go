@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// webSafe returns the n-th color of the web safe palette
+func webSafe(n int) color.RGBA {
+	return color.RGBA{uint8(n / 36 % 6 * 51), uint8(n / 6 % 6 * 51), uint8(n % 6 * 51), 0xff}
+}
+
+// captured returns the one file that was saved in dir
+func captured(t *testing.T, dir string) *os.File {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "hi-*"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("saved %v, want one file: %v", files, err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestScreenshot(t *testing.T) {
+	c := &Capture{Dir: t.TempDir()}
+	screen := ebiten.NewImage(W, H)
+	screen.Fill(webSafe(100))
+	c.Frame(screen)
+	c.Screenshot()
+	c.Frame(screen)
+	c.Frame(screen)
+	c.Wait()
+	img, err := png.Decode(captured(t, c.Dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != screen.Bounds() || color.RGBAModel.Convert(img.At(W/2, H/2)) != webSafe(100) {
+		t.Errorf("the screenshot is %v with %v in the middle", img.Bounds(), img.At(W/2, H/2))
+	}
+}
+
+// TestGIF records more frames than the ring keeps, and checks that the GIF
+// has the last ones, oldest first
+func TestGIF(t *testing.T) {
+	c := &Capture{Dir: t.TempDir()}
+	keep := GIF_SECONDS * ebiten.TPS() / GIF_EVERY
+	screen := ebiten.NewImage(W, H)
+	c.ToggleGIF()
+	for n := range keep + 50 {
+		screen.Fill(webSafe(n))
+		for range GIF_EVERY {
+			c.Frame(screen)
+		}
+	}
+	c.ToggleGIF()
+	c.Wait()
+	if c.Recording {
+		t.Error("still recording after saving")
+	}
+	anim, err := gif.DecodeAll(captured(t, c.Dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != keep {
+		t.Fatalf("the GIF has %d frames, want %d", len(anim.Image), keep)
+	}
+	for i, img := range anim.Image {
+		if got, want := color.RGBAModel.Convert(img.At(0, 0)), webSafe(50+i); got != want {
+			t.Fatalf("frame %d is %v, want %v", i, got, want)
+		}
+	}
+}
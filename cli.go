@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"hi/achievements"
+	"hi/batch"
+	"hi/level"
+	"hi/locale"
+	"hi/replay"
+	"hi/sfx"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// version is set when building a release, with -ldflags "-X main.version=1.0.0"
+var version = "dev"
+
+var (
+	scaleFlag      = flag.Int("scale", 2, "window size, as a multiple of 320x240")
+	fullscreenFlag = flag.Bool("fullscreen", false, "start in fullscreen")
+	vsyncFlag      = flag.Bool("vsync", true, "wait for the vertical blank before showing a frame")
+	tpsFlag        = flag.Int("tps", level.TPS, "simulation ticks per second")
+	seedFlag       = flag.Uint64("seed", 0, "seed for the random number generator, 0 picks one")
+	levelFlag      = flag.String("level", "levels/1.lvl", "the level to play")
+	replayFlag     = flag.String("replay", "", "play back a replay file")
+	recordFlag     = flag.String("record", "", "record the game to a replay file")
+	headlessFlag   = flag.Bool("headless", false, "run the simulation without a window, and print the result")
+	configFlag     = flag.String("config", "", "read flags from a file with one \"name = value\" per line")
+	versionFlag    = flag.Bool("version", false, "print the version and exit")
+)
+
+// MAX_HEADLESS_TICKS stops headless games that nobody is playing, in case the level never ends
+const MAX_HEADLESS_TICKS = 30 * 60 * level.TPS
+
+// SOUNDS is the file with the sound effects
+const SOUNDS = "data/sounds.txt"
+
+// MUSIC is the file with the music
+const MUSIC = "data/music.txt"
+
+func init() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage:\n")
+		fmt.Fprintf(out, "  hi [flags]                  play the game\n")
+		fmt.Fprintf(out, "  hi replay verify <file>...  check that replays still play out the same way\n")
+		fmt.Fprintf(out, "  hi replay events <file>     print what happened in a replay, tick by tick\n")
+		fmt.Fprintf(out, "  hi replay render <file>     draw every frame of a replay to PNG files, see hi replay render -help\n")
+		fmt.Fprintf(out, "  hi assets check             check that every image, shader, pattern, drop table, sound, song and level loads\n")
+		fmt.Fprintf(out, "  hi sfx export [name]...     write sound effects to WAV files, see hi sfx export -help\n")
+		fmt.Fprintf(out, "  hi sim [flags]              play many games without a window, and write statistics, see hi sim -help\n")
+		fmt.Fprintf(out, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+}
+
+// validateFlags checks the flags that can not be checked by the flag package
+func validateFlags() error {
+	online := *hostFlag != "" || *joinFlag != ""
+	switch {
+	case flag.NArg() > 0:
+		return fmt.Errorf("unknown command %q, see hi -help", flag.Arg(0))
+	case *scaleFlag < 1 || *scaleFlag > MAX_SCALE:
+		return fmt.Errorf("-scale must be from 1 to %d, not %d", MAX_SCALE, *scaleFlag)
+	case *tpsFlag < 1:
+		return fmt.Errorf("-tps must be at least 1, not %d", *tpsFlag)
+	case *hostFlag != "" && *joinFlag != "":
+		return errors.New("-host and -join can not be used together")
+	case *replayFlag != "" && *recordFlag != "":
+		return errors.New("-replay and -record can not be used together")
+	case online && (*replayFlag != "" || *recordFlag != ""):
+		return errors.New("online games can not be recorded or played back")
+	case online && *headlessFlag:
+		return errors.New("online games can not be headless")
+	case *delayFlag < 0:
+		return fmt.Errorf("-delay can not be negative")
+	case *lossFlag < 0 || *lossFlag > 1:
+		return fmt.Errorf("-loss must be between 0 and 1, not %g", *lossFlag)
+	}
+	return nil
+}
+
+// loadConfig reads flags from the -config file. Flags that are given on the
+// command line win over the ones in the file.
+func loadConfig() error {
+	if *configFlag == "" {
+		return nil
+	}
+	f, err := os.Open(*configFlag)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(text, "=")
+		name, value = strings.TrimSpace(name), strings.Trim(strings.TrimSpace(value), `"`)
+		switch {
+		case !found:
+			return fmt.Errorf("%s:%d: expected name = value", *configFlag, line)
+		case name == "config" || flag.Lookup(name) == nil:
+			return fmt.Errorf("%s:%d: unknown flag %q", *configFlag, line, name)
+		case given[name]:
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", *configFlag, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// videoFlags overrides the video settings with the flags that were given,
+// on the command line or in the -config file
+func videoFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "scale":
+			settings.Video.Scale = *scaleFlag
+		case "fullscreen":
+			settings.Video.Fullscreen = *fullscreenFlag
+		case "vsync":
+			settings.Video.VSync = *vsyncFlag
+		}
+	})
+}
+
+// loadData loads the bullet patterns and drop tables into the simulation
+func loadData() error {
+	return sim.LoadData(".")
+}
+
+// pickSeed returns the -seed flag, or a seed from the clock if it is 0
+func pickSeed() uint64 {
+	if *seedFlag != 0 {
+		return *seedFlag
+	}
+	return uint64(time.Now().UnixNano())
+}
+
+// runHeadless plays the game without a window, until the replay is over, or
+// until the game is over or the level is done, and prints how it went
+func runHeadless() error {
+	for world.Tick < MAX_HEADLESS_TICKS {
+		if playback != nil {
+			more, err := playback.Step(world)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+		} else {
+			if world.GameOver() || (world.Level.Done() && len(world.Enemies) == 0 && world.Boss == nil) {
+				break
+			}
+			if recording != nil {
+				recording.Add(world, nil)
+			} else {
+				world.Step(nil)
+			}
+		}
+		bus.Publish(world.Events)
+		mix.Update()
+	}
+	fmt.Printf("tick %d hash %016x\n", world.Tick, world.Hash())
+	for i, p := range world.Players {
+		if p.Joined || p.Score > 0 {
+			fmt.Printf("player %d score %d lives %d\n", i+1, p.Score, p.Lives)
+		}
+	}
+	return nil
+}
+
+// command runs a subcommand and returns the exit code
+func command(name string, args []string) int {
+	var err error
+	switch {
+	case name == "replay" && len(args) > 0 && args[0] == "verify":
+		err = verifyCommand(args[1:])
+	case name == "replay" && len(args) > 0 && args[0] == "events":
+		err = eventsCommand(args[1:])
+	case name == "replay" && len(args) > 0 && args[0] == "render":
+		err = replayRenderCommand(args[1:])
+	case name == "assets" && len(args) > 0 && args[0] == "check":
+		err = assetsCommand(args[1:])
+	case name == "sfx" && len(args) > 0 && args[0] == "export":
+		err = sfxCommand(args[1:])
+	case name == "sim":
+		err = simCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", strings.Join(append([]string{name}, args...), " "))
+		flag.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// verifyCommand plays back every given replay, and fails if one of them
+// does not play out the way it was recorded
+func verifyCommand(args []string) error {
+	fs := flag.NewFlagSet("hi replay verify", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi replay verify <file>...\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no replay files given")
+	}
+	if err := loadData(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, filename := range fs.Args() {
+		r, err := replay.Load(filename)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w, err := sim.LoadLevel(r.Level, r.Seed, r.Difficulty)
+		if err == nil {
+			err = r.Verify(w)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("%s: ok, %d ticks, hash %016x\n", filename, w.Tick, w.Hash())
+	}
+	return errors.Join(errs...)
+}
+
+// eventsCommand plays back a replay and prints every event, with the tick it
+// happened on, so that two versions of the game can be compared
+func eventsCommand(args []string) error {
+	fs := flag.NewFlagSet("hi replay events", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi replay events <file>\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("give one replay file")
+	}
+	if err := loadData(); err != nil {
+		return err
+	}
+	r, err := replay.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	w, err := sim.LoadLevel(r.Level, r.Seed, r.Difficulty)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(os.Stdout)
+	p := &replay.Player{Replay: r}
+	for {
+		more, err := p.Step(w)
+		if err != nil || !more {
+			if flushErr := out.Flush(); err == nil {
+				err = flushErr
+			}
+			return err
+		}
+		for _, e := range w.Events {
+			// the events happened during the tick before
+			fmt.Fprintf(out, "%d %s\n", w.Tick-1, e)
+		}
+	}
+}
+
+// assetsCommand loads every asset and reports all the problems it finds
+func assetsCommand(args []string) error {
+	fs := flag.NewFlagSet("hi assets check", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi assets check\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var errs []error
+	filenames := make([]string, 0, len(imageFiles))
+	for _, filename := range imageFiles {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		errs = append(errs, checkImage(filename))
+	}
+	shaders, err := filepath.Glob("shaders/*.kage")
+	errs = append(errs, err)
+	for _, filename := range shaders {
+		src, err := os.ReadFile(filename)
+		if err == nil {
+			_, err = ebiten.NewShader(src)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+		}
+	}
+	_, err = ebiten.NewShader([]byte(PALETTE_SHADER))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("palette shader: %w", err))
+	}
+	_, err = sfx.Load(SOUNDS)
+	errs = append(errs, err)
+	_, err = sfx.LoadSong(MUSIC)
+	errs = append(errs, err)
+	_, err = achievements.Load(ACHIEVEMENTS)
+	errs = append(errs, err)
+	catalogs, err := locale.LoadDir(LANGUAGES)
+	if err == nil {
+		err = checkLanguages(catalogs)
+	}
+	errs = append(errs, err)
+	if err := loadData(); err != nil {
+		// the levels can not be checked without the patterns and drop tables
+		return errors.Join(append(errs, err)...)
+	}
+	levels, err := filepath.Glob("levels/*.lvl")
+	errs = append(errs, err)
+	for _, filename := range levels {
+		_, err := sim.LoadLevel(filename, 0, sim.DefaultDifficulty)
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	fmt.Printf("%d images, %d shaders, %d languages and %d levels are fine\n", len(filenames), len(shaders), len(catalogs), len(levels))
+	return nil
+}
+
+// sfxCommand writes the sound effects to WAV files
+func sfxCommand(args []string) error {
+	fs := flag.NewFlagSet("hi sfx export", flag.ContinueOnError)
+	dir := fs.String("o", ".", "the directory to write the files to")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi sfx export [flags] [name]...\n\nWrites the named sounds, or all of them, to <name>.wav.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sounds, err := sfx.Load(SOUNDS)
+	if err != nil {
+		return err
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		for name := range sounds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		s := sounds[name]
+		if s == nil {
+			return fmt.Errorf("%s has no sound %q", SOUNDS, name)
+		}
+		filename := filepath.Join(*dir, name+".wav")
+		if err := s.SaveWAV(filename); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %.2fs\n", filename, s.Length())
+	}
+	return nil
+}
+
+// simCommand plays a game for each of a range of seeds, in parallel, and
+// writes how every player did as CSV or JSON
+func simCommand(args []string) error {
+	fs := flag.NewFlagSet("hi sim", flag.ContinueOnError)
+	var (
+		seeds   = fs.Int("seeds", 100, "number of games, one per seed")
+		first   = fs.Uint64("seed", 1, "the first seed")
+		ticks   = fs.Uint64("ticks", MAX_HEADLESS_TICKS, "the longest a game may go on, in ticks")
+		lvl     = fs.String("level", "levels/1.lvl", "the level to play")
+		pilot   = fs.String("pilot", "random", fmt.Sprintf("what plays the game, one of %s", strings.Join(batch.PilotNames(), ", ")))
+		players = fs.Int("players", 1, "number of players")
+		diff    = fs.String("difficulty", "normal", fmt.Sprintf("one of %s", strings.Join(difficulties, ", ")))
+		adapt   = fs.Bool("adaptive", false, "make the game easier or harder with how well the players do")
+		format  = fs.String("format", "csv", "csv or json")
+		output  = fs.String("o", "", "write to this file instead of stdout")
+		workers = fs.Int("workers", 0, "games that run at the same time, 0 for one per CPU")
+		minimum = fs.Duration("min-survival", 0, "fail if a player is in the game for less than this, for smoke tests")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hi sim [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	write := map[string]func(io.Writer, []batch.Result) error{"csv": batch.WriteCSV, "json": batch.WriteJSON}[*format]
+	switch {
+	case fs.NArg() > 0:
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	case *seeds < 1:
+		return fmt.Errorf("-seeds must be at least 1, not %d", *seeds)
+	case write == nil:
+		return fmt.Errorf("unknown format %q, it must be csv or json", *format)
+	}
+	difficulty, err := sim.FindDifficulty(*diff)
+	if err != nil {
+		return err
+	}
+	difficulty.Adaptive = *adapt
+	if err := loadData(); err != nil {
+		return err
+	}
+	l, err := level.Load(*lvl)
+	if err == nil {
+		// check the level against the enemies and bosses, once
+		err = sim.NewWorld().Load(l)
+	}
+	if err != nil {
+		return err
+	}
+	c := batch.Config{Level: l, Difficulty: difficulty, Ticks: *ticks, Players: *players, Pilot: *pilot, Workers: *workers}
+	for i := range *seeds {
+		c.Seeds = append(c.Seeds, *first+uint64(i))
+	}
+	start := time.Now()
+	results, err := batch.Run(c)
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+	}
+	if err := write(out, results); err != nil {
+		if *output != "" {
+			out.Close()
+		}
+		return err
+	}
+	if *output != "" {
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s, in %v\n", batch.Summary(results), time.Since(start).Round(time.Millisecond))
+	for _, r := range results {
+		if r.Survived < minimum.Seconds() && !r.Cleared {
+			return fmt.Errorf("player %d only survived %.1fs with seed %d", r.Player, r.Survived, r.Seed)
+		}
+	}
+	return nil
+}
+
+func checkImage(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+
+	"hi/level"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// MAX_TIMESCALE is the fastest the game can be run
+	MAX_TIMESCALE = 8
+	// MIN_TIMESCALE is the slowest the minus key goes
+	MIN_TIMESCALE = 0.125
+
+	REWIND_EVERY   = 6 // ticks between snapshots for rewinding
+	REWIND_SECONDS = 5 // how far back the game can be rewound
+)
+
+// Clock decides how many ticks the world is stepped in each Update, so that
+// the game can be paused, stepped a tick at a time and run slower or faster.
+// Online games always run at one tick per Update.
+type Clock struct {
+	Paused    bool
+	Scale     float64 // ticks per Update while not paused
+	Rewinding bool    // the rewind key is held
+	steps     int     // ticks to step while paused
+	acc       float64 // parts of a tick that have not been stepped yet
+}
+
+var clock = &Clock{Scale: 1}
+
+// Ticks returns how many ticks to step in this Update
+func (c *Clock) Ticks() int {
+	if c.Paused {
+		n := c.steps
+		c.steps = 0
+		return n
+	}
+	c.acc += c.Scale
+	n := int(c.acc)
+	c.acc -= float64(n)
+	return n
+}
+
+// Step pauses the game, and steps it n ticks in the next Update
+func (c *Clock) Step(n int) {
+	c.Paused = true
+	c.steps += n
+}
+
+// Update handles the time keys: P pauses, period steps one tick, minus and
+// equals halve and double the speed, and holding comma rewinds
+func (c *Clock) Update() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyP):
+		c.Paused = !c.Paused
+	case inpututil.IsKeyJustPressed(ebiten.KeyPeriod):
+		c.Step(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyMinus):
+		c.Scale = max(c.Scale/2, MIN_TIMESCALE)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEqual):
+		c.Scale = min(c.Scale*2, MAX_TIMESCALE)
+	}
+	// rewinding goes back at three times the normal speed, and the game
+	// stays paused afterwards, to look at the moment it went back to
+	d := inpututil.KeyPressDuration(ebiten.KeyComma)
+	c.Rewinding = d > 0
+	if c.Rewinding {
+		c.Paused = true
+		if d%2 == 1 {
+			history.Rewind()
+		}
+	}
+}
+
+// Draw shows when the game is not running at normal speed
+func (c *Clock) Draw(screen *ebiten.Image) {
+	var text string
+	switch {
+	case c.Rewinding:
+		text = "REWIND"
+	case c.Paused:
+		text = "PAUSED"
+	case c.Scale != 1:
+		text = fmt.Sprintf("x%g", c.Scale)
+	default:
+		return
+	}
+	ebitenutil.DebugPrintAt(screen, text, W-4-6*len(text), 16)
+}
+
+// History keeps snapshots of the last few seconds of the world, for rewinding
+type History struct {
+	snapshots []*sim.World // oldest first
+	world     *sim.World   // the world the snapshots are of
+}
+
+var history = &History{}
+
+// Save takes a snapshot of the world when it is time to. A new world, like
+// after starting a new game, starts a new history.
+func (h *History) Save(w *sim.World) {
+	if w != h.world {
+		clear(h.snapshots)
+		h.snapshots, h.world = h.snapshots[:0], w
+	}
+	if w.Tick%REWIND_EVERY != 0 {
+		return
+	}
+	if len(h.snapshots) == REWIND_SECONDS*level.TPS/REWIND_EVERY {
+		copy(h.snapshots, h.snapshots[1:])
+		h.snapshots = h.snapshots[:len(h.snapshots)-1]
+	}
+	h.snapshots = append(h.snapshots, w.Clone())
+}
+
+// Rewind goes back to the last snapshot before the current tick of the world.
+// Replays and recordings go back with it.
+func (h *History) Rewind() {
+	if world != h.world {
+		return
+	}
+	i := len(h.snapshots) - 1
+	for i >= 0 && h.snapshots[i].Tick >= world.Tick {
+		i--
+	}
+	if i < 0 {
+		return
+	}
+	clear(h.snapshots[i+1:])
+	h.snapshots = h.snapshots[:i+1]
+	world = h.snapshots[i].Clone()
+	h.world = world
+	cheated = true
+	if playback != nil {
+		playback.Seek(world.Tick)
+	}
+	if recording != nil {
+		recording.Truncate(world.Tick)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"hi/level"
+	"hi/sim"
+)
+
+func TestClockTicks(t *testing.T) {
+	for _, tt := range []struct {
+		scale float64
+		want  []int
+	}{
+		{1, []int{1, 1, 1, 1}},
+		{0.5, []int{0, 1, 0, 1}},
+		{0.25, []int{0, 0, 0, 1, 0, 0, 0, 1}},
+		{2, []int{2, 2}},
+		{0.75, []int{0, 1, 1, 1}},
+	} {
+		c := &Clock{Scale: tt.scale}
+		var got []int
+		for range tt.want {
+			got = append(got, c.Ticks())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("at %g: got %v, want %v", tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestClockPause(t *testing.T) {
+	c := &Clock{Scale: 0.5}
+	c.Ticks()
+	c.Step(1)
+	c.Step(2)
+	if !c.Paused {
+		t.Fatal("stepping did not pause the clock")
+	}
+	if got := []int{c.Ticks(), c.Ticks()}; !slices.Equal(got, []int{3, 0}) {
+		t.Errorf("paused, the clock stepped %v, want [3 0]", got)
+	}
+	// the half tick from before the pause is still there
+	c.Paused = false
+	if n := c.Ticks(); n != 1 {
+		t.Errorf("after the pause, the clock stepped %d, want 1", n)
+	}
+}
+
+// TestRewind plays a few seconds, and goes back a snapshot at a time
+func TestRewind(t *testing.T) {
+	world, history, cheated = sim.NewWorld(), &History{}, false
+	recording, playback = nil, nil
+	for range (REWIND_SECONDS + 1) * level.TPS {
+		history.Save(world)
+		world.Step(nil)
+	}
+	start := world.Tick
+	want := start - start%REWIND_EVERY
+	if want == start {
+		want -= REWIND_EVERY
+	}
+	oldest := start - REWIND_SECONDS*level.TPS
+	for ; want > oldest; want -= REWIND_EVERY {
+		tick := world.Tick
+		history.Rewind()
+		if world.Tick != want {
+			t.Fatalf("rewound from %d to %d, want %d", tick, world.Tick, want)
+		}
+	}
+	if !cheated {
+		t.Error("rewinding did not stop the statistics")
+	}
+	// the snapshots run out
+	for range REWIND_SECONDS * level.TPS / REWIND_EVERY {
+		history.Rewind()
+	}
+	if world.Tick < oldest {
+		t.Errorf("rewound to %d, which is more than %d seconds before %d", world.Tick, REWIND_SECONDS, start)
+	}
+
+	// a new game starts a new history
+	world = sim.NewWorld()
+	history.Save(world)
+	history.Rewind()
+	if world.Tick != 0 || len(history.snapshots) != 1 {
+		t.Error("rewound into the history of another game")
+	}
+}
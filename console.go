@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// CONSOLE_LINES is how many lines of output the console shows
+const CONSOLE_LINES = 8
+
+var consoleColor = color.NRGBA{0x00, 0x00, 0x00, 0xc0}
+
+// Console is the developer console, opened with the backtick key. The game
+// goes on while it is open, but the keys go to the console.
+type Console struct {
+	Open    bool
+	line    []rune
+	output  []string
+	history []string
+	recall  int // how far back in the history the up key went
+}
+
+var console = &Console{}
+
+// Command is a console command. It gets the words after the name, and
+// returns what to print.
+type Command struct {
+	Usage string
+	Cheat bool // changes the world, which would break replays and online games
+	Run   func(args []string) (string, error)
+}
+
+var commands map[string]Command
+
+func init() {
+	commands = map[string]Command{
+		"help": {Usage: "help", Run: helpCommand},
+		"spawn": {Usage: "spawn <enemy> <x> <y>", Cheat: true, Run: func(args []string) (string, error) {
+			if len(args) != 3 {
+				return "", errUsage
+			}
+			x, err1 := strconv.ParseFloat(args[1], 64)
+			y, err2 := strconv.ParseFloat(args[2], 64)
+			if err1 != nil || err2 != nil {
+				return "", errUsage
+			}
+			return "", world.Spawn(args[0], x, y)
+		}},
+		"god": {Usage: "god [player]", Cheat: true, Run: func(args []string) (string, error) {
+			p, err := consolePlayer(args)
+			if err != nil {
+				return "", err
+			}
+			p.God = !p.God
+			return "god " + onOff(p.God), nil
+		}},
+		"give": {Usage: "give weapon|bombs|lives <n> [player]", Cheat: true, Run: func(args []string) (string, error) {
+			if len(args) < 2 {
+				return "", errUsage
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 {
+				return "", errUsage
+			}
+			p, err := consolePlayer(args[2:])
+			if err != nil {
+				return "", err
+			}
+			switch args[0] {
+			case "weapon":
+				p.Weapon = min(max(n, 1), sim.MAX_WEAPON)
+			case "bombs":
+				p.Bombs = n
+			case "lives":
+				p.Lives = max(n, 1)
+			default:
+				return "", errUsage
+			}
+			return "", nil
+		}},
+		"tick": {Usage: "tick [step [n] | run]", Run: func(args []string) (string, error) {
+			switch {
+			case len(args) == 0:
+			case args[0] == "run" && len(args) == 1:
+				clock.Paused = false
+			case args[0] == "step" && len(args) <= 2:
+				n := 1
+				if len(args) == 2 {
+					var err error
+					if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+						return "", errUsage
+					}
+				}
+				clock.Step(n)
+			default:
+				return "", errUsage
+			}
+			return fmt.Sprintf("tick %d", world.Tick), nil
+		}},
+		"timescale": {Usage: "timescale [scale]", Run: func(args []string) (string, error) {
+			switch len(args) {
+			case 0:
+			case 1:
+				scale, err := strconv.ParseFloat(args[0], 64)
+				if err != nil || !(scale > 0 && scale <= MAX_TIMESCALE) {
+					return "", fmt.Errorf("the time scale goes from above 0 to %d", MAX_TIMESCALE)
+				}
+				clock.Scale = scale
+			default:
+				return "", errUsage
+			}
+			return fmt.Sprintf("timescale %g", clock.Scale), nil
+		}},
+	}
+}
+
+var errUsage = errors.New("usage")
+
+func helpCommand(args []string) (string, error) {
+	var lines []string
+	for _, c := range commands {
+		lines = append(lines, c.Usage)
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// consolePlayer returns the player numbered by the first argument, or player 1
+func consolePlayer(args []string) (*sim.Player, error) {
+	if len(args) == 0 {
+		return &world.Players[0], nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > sim.MAX_PLAYERS {
+		return nil, fmt.Errorf("there is no player %q", args[0])
+	}
+	return &world.Players[n-1], nil
+}
+
+// Update handles typing into the console
+func (c *Console) Update() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackquote), inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		c.Open = false
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		c.run(string(c.line))
+		c.line = c.line[:0]
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) && c.recall < len(c.history):
+		c.recall++
+		c.line = []rune(c.history[len(c.history)-c.recall])
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) && c.recall > 0:
+		c.recall--
+		c.line = c.line[:0]
+		if c.recall > 0 {
+			c.line = []rune(c.history[len(c.history)-c.recall])
+		}
+	}
+	// backspace repeats when held
+	if d := inpututil.KeyPressDuration(ebiten.KeyBackspace); (d == 1 || d > 30 && d%3 == 0) && len(c.line) > 0 {
+		c.line = c.line[:len(c.line)-1]
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if r != '`' {
+			c.line = append(c.line, r)
+		}
+	}
+}
+
+// run runs a line typed into the console
+func (c *Console) run(line string) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return
+	}
+	c.history, c.recall = append(c.history, line), 0
+	c.print("> " + line)
+	cmd, ok := commands[args[0]]
+	var (
+		out string
+		err error
+	)
+	switch {
+	case !ok:
+		err = fmt.Errorf("unknown command %q, try help", args[0])
+	case cmd.Cheat && (session != nil || recording != nil || playback != nil):
+		err = errors.New("not in online games, recordings and replays")
+	default:
+		cheated = cheated || cmd.Cheat
+		out, err = cmd.Run(args[1:])
+	}
+	if errors.Is(err, errUsage) {
+		err = errors.New("usage: " + cmd.Usage)
+	}
+	if err != nil {
+		out = err.Error()
+	}
+	if out != "" {
+		c.print(out)
+	}
+}
+
+func (c *Console) print(text string) {
+	c.output = append(c.output, strings.Split(text, "\n")...)
+	if len(c.output) > CONSOLE_LINES {
+		c.output = c.output[len(c.output)-CONSOLE_LINES:]
+	}
+}
+
+// Draw draws the console over the top of the screen
+func (c *Console) Draw(screen *ebiten.Image) {
+	const lineH = 12
+	vector.FillRect(screen, 0, 0, W, (CONSOLE_LINES+1)*lineH+4, consoleColor, false)
+	for i, line := range c.output {
+		ebitenutil.DebugPrintAt(screen, line, 4, i*lineH)
+	}
+	cursor := ""
+	if (ebiten.Tick()/30)%2 == 0 {
+		cursor = "_"
+	}
+	ebitenutil.DebugPrintAt(screen, "> "+string(c.line)+cursor, 4, CONSOLE_LINES*lineH)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	"hi/replay"
+	"hi/sim"
+)
+
+// useConsole starts a new game with a new console and clock, and runs the
+// lines in it. It returns the last line of output.
+func useConsole(lines ...string) string {
+	world, console, clock, cheated = sim.NewWorld(), &Console{}, &Clock{Scale: 1}, false
+	recording, playback, session = nil, nil, nil
+	return runConsole(lines...)
+}
+
+// runConsole runs the lines in the console, and returns the last line of output
+func runConsole(lines ...string) string {
+	for _, line := range lines {
+		console.run(line)
+	}
+	return console.output[len(console.output)-1]
+}
+
+func TestConsoleCheats(t *testing.T) {
+	if err := loadData(); err != nil {
+		t.Fatal(err)
+	}
+	useConsole("give weapon 99", "give bombs 5 2", "give lives 0", "god", "spawn grunt 100 50")
+	p1, p2 := &world.Players[0], &world.Players[1]
+	if p1.Weapon != sim.MAX_WEAPON || p2.Bombs != 5 || p1.Lives != 1 || !p1.God || p2.God {
+		t.Errorf("the players are %+v and %+v", *p1, *p2)
+	}
+	if len(world.Enemies) != 1 || world.Enemies[0].Kind.Name != "grunt" || world.Enemies[0].X != 100 {
+		t.Errorf("the enemies are %+v", world.Enemies)
+	}
+	if !cheated {
+		t.Error("cheats did not stop the statistics")
+	}
+	for _, tt := range []struct {
+		line string
+		out  string
+	}{
+		{"give weapon", "usage: give weapon|bombs|lives <n> [player]"},
+		{"give shields 1", "usage: give weapon|bombs|lives <n> [player]"},
+		{"give bombs -1", "usage: give weapon|bombs|lives <n> [player]"},
+		{"god 5", `there is no player "5"`},
+		{"spawn nobody 1 2", `unknown enemy "nobody"`},
+		{"spawn grunt here 2", "usage: spawn <enemy> <x> <y>"},
+		{"fly", `unknown command "fly", try help`},
+	} {
+		if out := runConsole(tt.line); out != tt.out {
+			t.Errorf("%s: got %q, want %q", tt.line, out, tt.out)
+		}
+	}
+}
+
+// TestConsoleRecording checks that cheats are refused while recording, and
+// that the other commands still work
+func TestConsoleRecording(t *testing.T) {
+	useConsole()
+	recording = replay.New("levels/1.lvl", 1, sim.DefaultDifficulty)
+	defer func() { recording = nil }()
+	if out := runConsole("god"); out != "not in online games, recordings and replays" || world.Players[0].God || cheated {
+		t.Errorf("god while recording printed %q", out)
+	}
+	if out := runConsole("timescale 2"); out != "timescale 2" || clock.Scale != 2 {
+		t.Errorf("timescale while recording printed %q", out)
+	}
+}
+
+func TestConsoleTime(t *testing.T) {
+	if out := useConsole("tick step 3"); out != "tick 0" || !clock.Paused {
+		t.Fatalf("tick step printed %q, and paused the clock: %v", out, clock.Paused)
+	}
+	if n := clock.Ticks(); n != 3 {
+		t.Errorf("stepped %d ticks, want 3", n)
+	}
+	if n := clock.Ticks(); n != 0 {
+		t.Errorf("stepped %d more ticks while paused", n)
+	}
+	if runConsole("tick run"); clock.Paused {
+		t.Error("tick run did not unpause the clock")
+	}
+	if out := runConsole("timescale 0.25"); out != "timescale 0.25" || clock.Scale != 0.25 {
+		t.Errorf("timescale 0.25 printed %q", out)
+	}
+	for _, line := range []string{"timescale 0", "timescale 9", "timescale fast"} {
+		if out := runConsole(line); out != "the time scale goes from above 0 to 8" || clock.Scale != 0.25 {
+			t.Errorf("%s: printed %q, and the scale is %g", line, out, clock.Scale)
+		}
+	}
+	if out := runConsole("tick step 0"); out != "usage: tick [step [n] | run]" {
+		t.Errorf("tick step 0 printed %q", out)
+	}
+}
@@ -0,0 +1,153 @@
+// Package curve provides parametric curves and constant speed travel along them.
+package curve
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Point is a position in screen coordinates
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Curve is a piecewise parametric curve. Each segment is evaluated with t from 0 to 1.
+type Curve interface {
+	Segments() int
+	At(segment int, t float64) Point
+}
+
+// Polyline is a curve of straight lines between the points
+type Polyline []Point
+
+func (p Polyline) Segments() int {
+	return len(p) - 1
+}
+
+func (p Polyline) At(segment int, t float64) Point {
+	a, b := p[segment], p[segment+1]
+	return Point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+}
+
+// Bezier is a chain of cubic Bezier curves. The points are the start point,
+// followed by two control points and an end point for every segment.
+type Bezier []Point
+
+func (b Bezier) Segments() int {
+	return (len(b) - 1) / 3
+}
+
+func (b Bezier) At(segment int, t float64) Point {
+	p0, p1, p2, p3 := b[segment*3], b[segment*3+1], b[segment*3+2], b[segment*3+3]
+	u := 1 - t
+	c0, c1, c2, c3 := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return Point{
+		c0*p0.X + c1*p1.X + c2*p2.X + c3*p3.X,
+		c0*p0.Y + c1*p1.Y + c2*p2.Y + c3*p3.Y,
+	}
+}
+
+// CatmullRom is a spline that passes through all of its points
+type CatmullRom []Point
+
+func (c CatmullRom) Segments() int {
+	return len(c) - 1
+}
+
+func (c CatmullRom) At(segment int, t float64) Point {
+	// The first and last points are repeated, so that the spline reaches them
+	p0 := c[max(segment-1, 0)]
+	p1 := c[segment]
+	p2 := c[segment+1]
+	p3 := c[min(segment+2, len(c)-1)]
+	t2, t3 := t*t, t*t*t
+	f := func(a, b, c, d float64) float64 {
+		return 0.5 * (2*b + (c-a)*t + (2*a-5*b+4*c-d)*t2 + (3*b-a-3*c+d)*t3)
+	}
+	return Point{f(p0.X, p1.X, p2.X, p3.X), f(p0.Y, p1.Y, p2.Y, p3.Y)}
+}
+
+// New returns a curve of the given kind, which is "line", "bezier" or "spline"
+func New(kind string, points []Point) (Curve, error) {
+	switch kind {
+	case "line":
+		if len(points) < 2 {
+			return nil, errors.New("a line needs at least two points")
+		}
+		return Polyline(points), nil
+	case "bezier":
+		if len(points) < 4 || (len(points)-1)%3 != 0 {
+			return nil, errors.New("a bezier curve needs 4, 7, 10... points")
+		}
+		return Bezier(points), nil
+	case "spline":
+		if len(points) < 2 {
+			return nil, errors.New("a spline needs at least two points")
+		}
+		return CatmullRom(points), nil
+	}
+	return nil, errors.New("unknown curve type " + kind)
+}
+
+// SAMPLES is the number of samples per segment used for measuring arc length
+const SAMPLES = 32
+
+// Path is a curve that is parameterised by distance, for constant speed travel
+type Path struct {
+	Curve Curve
+	dist  []float64 // distance from the start, per sample
+}
+
+// NewPath measures the curve
+func NewPath(c Curve) *Path {
+	n := c.Segments() * SAMPLES
+	p := &Path{Curve: c, dist: make([]float64, n+1)}
+	prev := c.At(0, 0)
+	for i := 1; i <= n; i++ {
+		pt := p.sample(i)
+		p.dist[i] = p.dist[i-1] + math.Hypot(pt.X-prev.X, pt.Y-prev.Y)
+		prev = pt
+	}
+	return p
+}
+
+// sample returns the point for sample number i
+func (p *Path) sample(i int) Point {
+	seg, j := i/SAMPLES, i%SAMPLES
+	if seg == p.Curve.Segments() {
+		return p.Curve.At(seg-1, 1)
+	}
+	return p.Curve.At(seg, float64(j)/SAMPLES)
+}
+
+// Length returns the total length of the path
+func (p *Path) Length() float64 {
+	return p.dist[len(p.dist)-1]
+}
+
+// At returns the point at distance d along the path
+func (p *Path) At(d float64) Point {
+	n := len(p.dist) - 1
+	if d <= 0 {
+		return p.sample(0)
+	}
+	if d >= p.Length() {
+		return p.sample(n)
+	}
+	// find the first sample that is further along than d
+	i := sort.SearchFloat64s(p.dist, d)
+	seg := p.dist[i] - p.dist[i-1]
+	t := 0.0
+	if seg > 0 {
+		t = (d - p.dist[i-1]) / seg
+	}
+	// interpolate the curve parameter, not the sampled points, to stay on the curve
+	u := (float64(i-1) + t) / SAMPLES
+	s := int(u)
+	if s >= p.Curve.Segments() {
+		s = p.Curve.Segments() - 1
+	}
+	return p.Curve.At(s, u-float64(s))
+}
@@ -0,0 +1,98 @@
+package curve
+
+import (
+	"math"
+	"testing"
+)
+
+// near returns true if the points are less than d apart
+func near(a, b Point, d float64) bool {
+	return math.Hypot(a.X-b.X, a.Y-b.Y) < d
+}
+
+func TestPolyline(t *testing.T) {
+	p := NewPath(Polyline{{0, 0}, {30, 40}, {30, 100}})
+	if math.Abs(p.Length()-110) > 1e-9 {
+		t.Errorf("the length is %g, want 110", p.Length())
+	}
+	for _, tt := range []struct {
+		d    float64
+		want Point
+	}{
+		{-5, Point{0, 0}},
+		{25, Point{15, 20}},
+		{50, Point{30, 40}},
+		{80, Point{30, 70}},
+		{200, Point{30, 100}},
+	} {
+		if got := p.At(tt.d); !near(got, tt.want, 1e-9) {
+			t.Errorf("at %g the path is at %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+// TestConstantSpeed checks that equal steps along a path are equally long,
+// on curves whose parameter runs at very different speeds
+func TestConstantSpeed(t *testing.T) {
+	for name, c := range map[string]Curve{
+		"line":   Bezier{{0, 0}, {0, 0}, {100, 0}, {300, 0}},
+		"bezier": Bezier{{0, 0}, {0, 0}, {0, 0}, {100, 0}, {250, 0}, {300, 50}, {300, 200}},
+		"spline": CatmullRom{{0, 0}, {40, 40}, {200, 60}, {220, 140}},
+	} {
+		p := NewPath(c)
+		const steps = 100
+		step := p.Length() / steps
+		prev := p.At(0)
+		for i := 1; i <= steps; i++ {
+			pt := p.At(float64(i) * step)
+			// the curves are sampled, so the steps are only about as long
+			// as each other, but in equal steps of the curve parameter the
+			// bezier curves would go from under a pixel to over 20
+			if d := math.Hypot(pt.X-prev.X, pt.Y-prev.Y); d < 0.95*step || d > 1.05*step {
+				t.Errorf("%s: step %d is %g long, want %g", name, i, d, step)
+				break
+			}
+			prev = pt
+		}
+		if end := c.At(c.Segments()-1, 1); !near(p.At(p.Length()), end, 1e-6) {
+			t.Errorf("%s: the path ends at %v, want %v", name, p.At(p.Length()), end)
+		}
+	}
+}
+
+func TestCatmullRom(t *testing.T) {
+	c := CatmullRom{{0, 0}, {50, 80}, {120, 20}, {160, 90}}
+	for i := range c.Segments() {
+		if got := c.At(i, 0); got != c[i] {
+			t.Errorf("segment %d starts at %v, want %v", i, got, c[i])
+		}
+		if got := c.At(i, 1); !near(got, c[i+1], 1e-9) {
+			t.Errorf("segment %d ends at %v, want %v", i, got, c[i+1])
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	points := []Point{{0, 0}, {1, 1}, {2, 0}, {3, 1}, {4, 0}}
+	for _, tt := range []struct {
+		kind string
+		n    int
+		err  string
+	}{
+		{"line", 5, ""},
+		{"line", 1, "a line needs at least two points"},
+		{"bezier", 4, ""},
+		{"bezier", 5, "a bezier curve needs 4, 7, 10... points"},
+		{"spline", 2, ""},
+		{"spline", 1, "a spline needs at least two points"},
+		{"arc", 3, "unknown curve type arc"},
+	} {
+		c, err := New(tt.kind, points[:tt.n])
+		switch {
+		case tt.err == "" && (err != nil || c.Segments() < 1):
+			t.Errorf("a %s of %d points: %v", tt.kind, tt.n, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("a %s of %d points: got %v, want %s", tt.kind, tt.n, err, tt.err)
+		}
+	}
+}
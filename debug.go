@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// debug is toggled with F3 and draws the debug overlay on top of the frame
+var debug bool
+
+var (
+	pathColor   = color.RGBA{0x00, 0x92, 0x92, 0xff}
+	hitboxColor = color.RGBA{0xff, 0xff, 0x00, 0xff}
+	armourColor = color.RGBA{0x92, 0x92, 0x92, 0xff}
+	enemyColor  = color.RGBA{0xff, 0x49, 0x00, 0xff}
+	bulletColor = color.RGBA{0xff, 0x00, 0xff, 0xff}
+	pickupColor = color.RGBA{0x00, 0xff, 0x49, 0xff}
+	cellColor   = color.NRGBA{0x00, 0x49, 0xff, 0x30}
+)
+
+// drawDebug draws the cells of the spatial hash that have enemies in them,
+// the movement path of every enemy that follows one, the hitboxes of
+// everything, the frame rate, the seed and tick, how many things there are,
+// and the state of online games
+func drawDebug(screen *ebiten.Image) {
+	for _, c := range world.Cells() {
+		vector.FillRect(screen, float32(c.X), float32(c.Y), sim.CELL, sim.CELL, cellColor, false)
+		drawHitbox(screen, c.X, c.Y, sim.CELL, sim.CELL, cellColor)
+	}
+
+	for i := 0; i < len(world.Enemies); i++ {
+		e := &world.Enemies[i]
+		if e.Path == nil {
+			continue
+		}
+		// the path goes through the middle of the sprite
+		ox, oy := e.Start.X+sim.ENEMY_W/2, e.Start.Y+sim.ENEMY_H/2
+		prev := e.Path.At(0)
+		for d := 4.0; d < e.Path.Length()+4; d += 4 {
+			p := e.Path.At(d)
+			vector.StrokeLine(screen, float32(ox+prev.X), float32(oy+prev.Y), float32(ox+p.X), float32(oy+p.Y), 1, pathColor, false)
+			prev = p
+		}
+	}
+
+	for _, e := range world.Enemies {
+		drawHitbox(screen, e.X, e.Y, sim.ENEMY_W, sim.ENEMY_H, enemyColor)
+	}
+	for _, b := range world.Bullets {
+		if b.Hostile {
+			drawHitbox(screen, b.X, b.Y, sim.ENEMY_BULLET_W, sim.ENEMY_BULLET_H, bulletColor)
+		}
+	}
+	for _, p := range world.Pickups {
+		drawHitbox(screen, p.X, p.Y, sim.PICKUP_W, sim.PICKUP_H, pickupColor)
+	}
+	for _, p := range world.Players {
+		if p.Joined {
+			drawHitbox(screen, p.Ship.X+(sim.SHIP_W-sim.HITBOX)/2, p.Ship.Y+(sim.SHIP_H-sim.HITBOX)/2, sim.HITBOX, sim.HITBOX, hitboxColor)
+		}
+	}
+
+	if b := world.Boss; b != nil {
+		for _, p := range b.Parts {
+			if p.Kind.HP > 0 && p.HP <= 0 {
+				continue
+			}
+			clr := hitboxColor
+			if p.Kind.Armour {
+				clr = armourColor
+			}
+			drawHitbox(screen, b.X+p.Kind.X, b.Y+p.Kind.Y, p.Kind.W, p.Kind.H, clr)
+		}
+	}
+
+	stats := fmt.Sprintf("FPS %.1f TPS %.1f\nSEED %d TICK %d\nBULLETS %d MISSILES %d\nENEMIES %d PICKUPS %d\n%s INTENSITY %.2f",
+		ebiten.ActualFPS(), ebiten.ActualTPS(), world.Seed, world.Tick,
+		len(world.Bullets), len(world.Missiles), len(world.Enemies), len(world.Pickups),
+		strings.ToUpper(world.Difficulty.Name), world.Intensity)
+	ebitenutil.DebugPrintAt(screen, stats+"\n"+netStats(), 4, 12)
+}
+
+func drawHitbox(screen *ebiten.Image, x, y, w, h float64, clr color.Color) {
+	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, clr, false)
+}
@@ -0,0 +1,111 @@
+// Package drops parses drop tables, which say what enemies leave behind.
+//
+// Each line has the name of an enemy or boss, the chance that it drops
+// something when destroyed, and the items it can drop with their weights:
+//
+//	# enemy  chance  item=weight ...
+//	grunt    0.05    weapon=3 speed=2 shield=1
+//	warden   1       life=1
+//
+// A grunt drops something one time in twenty, and then it is a weapon
+// upgrade half of the time.
+package drops
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"hi/textfile"
+)
+
+// Entry is an item in a drop table
+type Entry struct {
+	Item   string
+	Weight int
+}
+
+// Table is what a single enemy type can drop
+type Table struct {
+	Filename string
+	Line     int
+	Chance   float64
+	Items    []Entry
+	Total    int // sum of the weights
+}
+
+// Tables are drop tables by enemy name
+type Tables map[string]*Table
+
+// Load reads and parses a drop table file
+func Load(filename string) (Tables, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads drop tables from r. The filename is only used for error messages.
+func Parse(r io.Reader, filename string) (Tables, error) {
+	tables := make(Tables)
+	errorf := func(line int, format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, errorf(line, "usage: <enemy> <chance> <item>=<weight> ...")
+		}
+		name := fields[0]
+		if tables[name] != nil {
+			return nil, errorf(line, "%s already has a drop table on line %d", name, tables[name].Line)
+		}
+		t := &Table{Filename: filename, Line: line}
+		var err error
+		if t.Chance, err = strconv.ParseFloat(fields[1], 64); err != nil || t.Chance < 0 || t.Chance > 1 {
+			return nil, errorf(line, "invalid chance %q, expected a number from 0 to 1", fields[1])
+		}
+		for _, f := range fields[2:] {
+			item, weight, found := strings.Cut(f, "=")
+			if !found {
+				return nil, errorf(line, "invalid entry %q, expected item=weight", f)
+			}
+			w, err := strconv.Atoi(weight)
+			if err != nil || w < 1 {
+				return nil, errorf(line, "invalid weight %q for %s", weight, item)
+			}
+			t.Items = append(t.Items, Entry{item, w})
+			t.Total += w
+		}
+		tables[name] = t
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Errorf returns a *textfile.Error for the line of this table
+func (t *Table) Errorf(format string, args ...any) error {
+	return textfile.Errorf(t.Filename, t.Line, format, args...)
+}
+
+// Pick returns the item for a number in [0, Total)
+func (t *Table) Pick(n int) string {
+	for _, e := range t.Items {
+		if n < e.Weight {
+			return e.Item
+		}
+		n -= e.Weight
+	}
+	return t.Items[len(t.Items)-1].Item
+}
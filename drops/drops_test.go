@@ -0,0 +1,66 @@
+package drops
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+func TestParse(t *testing.T) {
+	src := `# enemy  chance  item=weight ...
+grunt    0.05    weapon=3 speed=2 # a comment
+warden   1       life=1
+`
+	tables, err := Parse(strings.NewReader(src), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grunt := tables["grunt"]
+	if len(tables) != 2 || grunt == nil || tables["warden"] == nil {
+		t.Fatalf("tables are %v", tables)
+	}
+	want := &Table{Filename: "test.txt", Line: 2, Chance: 0.05, Items: []Entry{{"weapon", 3}, {"speed", 2}}, Total: 5}
+	if grunt.Filename != want.Filename || grunt.Line != want.Line || grunt.Chance != want.Chance ||
+		!slices.Equal(grunt.Items, want.Items) || grunt.Total != want.Total {
+		t.Errorf("grunt is %+v, want %+v", grunt, want)
+	}
+	if err := grunt.Errorf("no such item"); err.Error() != "test.txt:2: no such item" {
+		t.Errorf("the error of a table is %q", err)
+	}
+}
+
+func TestPick(t *testing.T) {
+	table := &Table{Items: []Entry{{"weapon", 3}, {"speed", 2}}, Total: 5}
+	for n, want := range []string{"weapon", "weapon", "weapon", "speed", "speed"} {
+		if got := table.Pick(n); got != want {
+			t.Errorf("Pick(%d) is %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"grunt 0.5", 1, "usage: <enemy> <chance> <item>=<weight> ..."},
+		{"grunt 0.5 life=1\n\ngrunt 0.5 life=1", 3, "grunt already has a drop table on line 1"},
+		{"# chance\ngrunt 2 life=1", 2, `invalid chance "2", expected a number from 0 to 1`},
+		{"grunt x life=1", 1, `invalid chance "x", expected a number from 0 to 1`},
+		{"grunt 0.5 life", 1, `invalid entry "life", expected item=weight`},
+		{"grunt 0.5 life=0", 1, `invalid weight "0" for life`},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.txt")
+		textfile.Check(t, err, "bad.txt", tt.line, tt.msg)
+	}
+}
+
+// TestLoad loads the drop tables that ship with the game
+func TestLoad(t *testing.T) {
+	if _, err := Load("../data/drops.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
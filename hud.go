@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+var (
+	healthColor = color.RGBA{0xdb, 0x00, 0x49, 0xff}
+	emptyColor  = color.RGBA{0x49, 0x00, 0x24, 0xff}
+	shieldColor = color.RGBA{0x00, 0xdb, 0xdb, 0xff}
+
+	focusColor    = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	focusRimColor = color.RGBA{0xdb, 0x00, 0x00, 0xff}
+
+	blastColor = color.RGBA{0xff, 0xdb, 0x92, 0xff}
+
+	// playerColors tint the ship, the bullets and the HUD of each player
+	playerColors = [sim.MAX_PLAYERS]color.RGBA{
+		{0xff, 0xff, 0xff, 0xff},
+		{0xff, 0x92, 0x92, 0xff},
+		{0x92, 0xdb, 0xff, 0xff},
+		{0xb6, 0xff, 0x92, 0xff},
+	}
+)
+
+// drawHUD draws the score, lives, bombs, weapon and buff timers of every
+// player, the boss health and the game over text
+func drawHUD(screen *ebiten.Image) {
+	// one column per player along the bottom, with the buff timers in whole seconds above
+	for i, p := range world.Players {
+		x := 4 + i*W/sim.MAX_PLAYERS
+		vector.FillRect(screen, float32(x), H-26, 3, 22, playerColors[i], false)
+		x += 6
+		if !p.Joined {
+			ebitenutil.DebugPrintAt(screen, msg("hud.join", i+1), x, H-16)
+			if p.Score > 0 {
+				ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%07d", p.Score), x, H-28)
+			}
+			continue
+		}
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%07d", p.Score), x, H-28)
+		ebitenutil.DebugPrintAt(screen, msg("hud.status", p.Lives, p.Bombs, p.Weapon), x, H-16)
+		y := H - 40
+		for _, buff := range []struct {
+			name  string
+			ticks uint64
+		}{{"hud.shield", p.ShieldTime}, {"hud.speed", p.SpeedTime}} {
+			if buff.ticks > 0 {
+				ebitenutil.DebugPrintAt(screen, msg(buff.name, (buff.ticks+59)/60), x, y)
+				y -= 12
+			}
+		}
+	}
+
+	if b := world.Boss; b != nil {
+		// one health bar per phase, the phases that are done are empty
+		const gap, x, y, h = 2, 8, 4, 4
+		maxHP := b.MaxHP()
+		width := float32(W-2*x-gap*(len(maxHP)-1)) / float32(len(maxHP))
+		for i, hp := range maxHP {
+			bx := x + float32(i)*(width+gap)
+			vector.FillRect(screen, bx, y, width, h, emptyColor, false)
+			left := float32(0)
+			switch {
+			case i > b.Phase:
+				left = 1
+			case i == b.Phase:
+				left = float32(b.HP) / float32(hp)
+			}
+			vector.FillRect(screen, bx, y, width*left, h, healthColor, false)
+		}
+	}
+
+	if world.GameOver() {
+		text := msg("hud.game-over")
+		ebitenutil.DebugPrintAt(screen, text, W/2-textWidth(text)/2, H/2-8)
+	}
+}
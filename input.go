@@ -0,0 +1,195 @@
+package main
+
+import (
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Device is something a player can play with, like half of the keyboard or a gamepad
+type Device interface {
+	// Input returns the buttons that are held down right now
+	Input() sim.Input
+	// Connected returns false when the device has been unplugged
+	Connected() bool
+}
+
+// Keyboard is a set of keys on the keyboard, so that two players can share it
+type Keyboard struct {
+	Left, Right, Up, Down      ebiten.Key
+	Fire, Missile, Focus, Bomb ebiten.Key
+	Join, Leave                ebiten.Key
+}
+
+// defaultKeyboards are the keys that the two keyboard players start out with.
+// The first one is the arrow keys, and the second one the left hand side of the keyboard.
+var defaultKeyboards = [2]Keyboard{
+	{
+		Left: ebiten.KeyArrowLeft, Right: ebiten.KeyArrowRight, Up: ebiten.KeyArrowUp, Down: ebiten.KeyArrowDown,
+		Fire: ebiten.KeySpace, Missile: ebiten.KeyX, Focus: ebiten.KeyShiftRight, Bomb: ebiten.KeyB,
+		Join: ebiten.KeyEnter, Leave: ebiten.KeyBackspace,
+	},
+	{
+		Left: ebiten.KeyA, Right: ebiten.KeyD, Up: ebiten.KeyW, Down: ebiten.KeyS,
+		Fire: ebiten.KeyF, Missile: ebiten.KeyG, Focus: ebiten.KeyShiftLeft, Bomb: ebiten.KeyR,
+		Join: ebiten.Key1, Leave: ebiten.Key2,
+	},
+}
+
+// keyboard1 and keyboard2 are the keys in use, which can be changed in the options menu
+var keyboard1, keyboard2 = newKeyboard(0), newKeyboard(1)
+
+func newKeyboard(i int) *Keyboard {
+	k := defaultKeyboards[i]
+	return &k
+}
+
+func (k *Keyboard) Input() sim.Input {
+	var in sim.Input
+	for _, b := range []struct {
+		key    ebiten.Key
+		button sim.Input
+	}{
+		{k.Left, sim.Left}, {k.Right, sim.Right}, {k.Up, sim.Up}, {k.Down, sim.Down},
+		{k.Missile, sim.Missile}, {k.Focus, sim.Focus}, {k.Bomb, sim.Bomb},
+		{k.Join, sim.Join}, {k.Leave, sim.Leave},
+	} {
+		if ebiten.IsKeyPressed(b.key) {
+			in |= b.button
+		}
+	}
+	// one bullet per key press, or a stream of them with hold to fire
+	if fired(inpututil.KeyPressDuration(k.Fire)) {
+		in |= sim.Fire
+	}
+	return in
+}
+
+func (k *Keyboard) Connected() bool {
+	return true
+}
+
+// Gamepad is a gamepad with the standard layout: the left stick or the
+// d-pad moves, the bottom face button fires, the right one fires missiles,
+// the left one drops bombs, and the left shoulder button focuses.
+type Gamepad struct {
+	ID ebiten.GamepadID
+}
+
+// STICK_DEADZONE is how far the stick must be pushed before it counts
+const STICK_DEADZONE = 0.4
+
+func (g *Gamepad) Input() sim.Input {
+	var in sim.Input
+	for _, b := range []struct {
+		button ebiten.StandardGamepadButton
+		input  sim.Input
+	}{
+		{ebiten.StandardGamepadButtonLeftLeft, sim.Left},
+		{ebiten.StandardGamepadButtonLeftRight, sim.Right},
+		{ebiten.StandardGamepadButtonLeftTop, sim.Up},
+		{ebiten.StandardGamepadButtonLeftBottom, sim.Down},
+		{ebiten.StandardGamepadButtonRightRight, sim.Missile},
+		{ebiten.StandardGamepadButtonFrontTopLeft, sim.Focus},
+		{ebiten.StandardGamepadButtonRightLeft, sim.Bomb},
+		{ebiten.StandardGamepadButtonCenterRight, sim.Join},
+		{ebiten.StandardGamepadButtonCenterLeft, sim.Leave},
+	} {
+		if ebiten.IsStandardGamepadButtonPressed(g.ID, b.button) {
+			in |= b.input
+		}
+	}
+	if fired(inpututil.StandardGamepadButtonPressDuration(g.ID, ebiten.StandardGamepadButtonRightBottom)) {
+		in |= sim.Fire
+	}
+	x := ebiten.StandardGamepadAxisValue(g.ID, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	y := ebiten.StandardGamepadAxisValue(g.ID, ebiten.StandardGamepadAxisLeftStickVertical)
+	switch {
+	case x < -STICK_DEADZONE:
+		in |= sim.Left
+	case x > STICK_DEADZONE:
+		in |= sim.Right
+	}
+	switch {
+	case y < -STICK_DEADZONE:
+		in |= sim.Up
+	case y > STICK_DEADZONE:
+		in |= sim.Down
+	}
+	return in
+}
+
+func (g *Gamepad) Connected() bool {
+	return !inpututil.IsGamepadJustDisconnected(g.ID)
+}
+
+// Seats keeps track of which device controls which player
+type Seats struct {
+	Devices [sim.MAX_PLAYERS]Device
+	idle    []Device // devices that are not controlling a player
+}
+
+// NewSeats gives the first player the arrow keys, and lets the other
+// keyboard half and the gamepads that are plugged in join in
+func NewSeats() *Seats {
+	s := &Seats{idle: []Device{keyboard2}}
+	s.Devices[0] = keyboard1
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			s.idle = append(s.idle, &Gamepad{id})
+		}
+	}
+	return s
+}
+
+// Inputs checks for new gamepads, seats devices that press join at the
+// lowest free player, and returns the input of every player
+func (s *Seats) Inputs(w *sim.World) []sim.Input {
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			s.idle = append(s.idle, &Gamepad{id})
+		}
+	}
+	idle := s.idle[:0]
+	for _, d := range s.idle {
+		if !d.Connected() {
+			continue
+		}
+		if i := s.free(w); i >= 0 && d.Input().Has(sim.Join) {
+			s.Devices[i] = d
+			continue
+		}
+		idle = append(idle, d)
+	}
+	s.idle = idle
+
+	inputs := make([]sim.Input, sim.MAX_PLAYERS)
+	for i, d := range s.Devices {
+		if d == nil {
+			continue
+		}
+		if !d.Connected() {
+			// unplugging the device drops the player out
+			inputs[i] = sim.Leave
+			s.Devices[i] = nil
+			continue
+		}
+		inputs[i] = d.Input()
+		if inputs[i].Has(sim.Leave) {
+			s.Devices[i] = nil
+			s.idle = append(s.idle, d)
+		}
+	}
+	return inputs
+}
+
+// free returns the lowest player that has no device and is not playing, or -1
+func (s *Seats) free(w *sim.World) int {
+	for i, d := range s.Devices {
+		if d == nil && !w.Players[i].Joined {
+			return i
+		}
+	}
+	return -1
+}
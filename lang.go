@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"hi/locale"
+)
+
+// LANGUAGES is the directory with the messages of every language
+const LANGUAGES = "lang"
+
+// BASE_LANGUAGE is what the other languages are translated from, and what
+// the game falls back to
+const BASE_LANGUAGE = "en"
+
+var (
+	// languages are the catalogs by language code
+	languages map[string]*locale.Catalog
+
+	// lang is the language that the game is shown in
+	lang *locale.Catalog
+)
+
+// loadLanguages loads and checks every language, and picks the one from the settings
+func loadLanguages() error {
+	catalogs, err := locale.LoadDir(LANGUAGES)
+	if err != nil {
+		return err
+	}
+	if err := checkLanguages(catalogs); err != nil {
+		return err
+	}
+	languages = catalogs
+	useLanguage(settings.Language)
+	return nil
+}
+
+// checkLanguages checks that every language has the same messages as
+// English, and only characters that the font has
+func checkLanguages(catalogs map[string]*locale.Catalog) error {
+	base := catalogs[BASE_LANGUAGE]
+	if base == nil {
+		return fmt.Errorf("%s: there is no %s.txt", LANGUAGES, BASE_LANGUAGE)
+	}
+	var errs []error
+	for _, c := range catalogs {
+		if c != base {
+			errs = append(errs, c.Check(base)...)
+		}
+		errs = append(errs, c.Glyphs(fontHas)...)
+	}
+	// the order of the map is random
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// fontHas returns true if the font that the text is drawn with has r. The
+// debug font of Ebiten has the printable characters of Latin-1.
+func fontHas(r rune) bool {
+	return r >= 0x20 && r < 0x7f || r >= 0xa0 && r <= 0xff
+}
+
+// useLanguage shows the game in the language with the given code, or in
+// the language of the system if code is "". Languages that there are no
+// messages for are shown in English.
+func useLanguage(code string) {
+	if code == "" {
+		code = systemLanguage()
+	}
+	if lang = languages[code]; lang == nil {
+		lang = languages[BASE_LANGUAGE]
+	}
+}
+
+// systemLanguage returns the language code from the environment, like de
+// for LANG=de_DE.UTF-8
+func systemLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		code, _, _ := strings.Cut(value, "_")
+		code, _, _ = strings.Cut(code, ".")
+		if code == "no" {
+			// Norwegian, which is written in bokmål by most
+			code = "nb"
+		}
+		return code
+	}
+	return BASE_LANGUAGE
+}
+
+// languageCodes returns the codes of the languages, in order
+func languageCodes() []string {
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// msg returns a message in the current language
+func msg(id string, args ...any) string {
+	return lang.Text(id, args...)
+}
+
+// plural returns the form of a message for n, in the current language
+func plural(id string, n int, args ...any) string {
+	return lang.Plural(id, n, args...)
+}
+
+// textWidth returns how wide the text is in the debug font, in pixels
+func textWidth(text string) int {
+	return 6 * utf8.RuneCountInString(text)
+}
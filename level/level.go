@@ -0,0 +1,313 @@
+// Package level parses the line based level format.
+//
+// A level file is a list of directives and timed events, one per line:
+//
+//	# comments start with a hash
+//	name First Contact
+//	path dive 0,0 0,80 60,140 60,260
+//	path loop spline 0,0 0,100 80,140 120,60 40,40
+//
+//	0    background #000010
+//	2s   spawn grunt 160 -16
+//	+30  formation v 5 grunt 160 -16 spacing=20 path=dive
+//	60s  boss warden
+//
+// Paths are lines, cubic bezier curves or Catmull-Rom splines, and their
+// points are relative to the spawn position of the enemy that follows them.
+//
+// Event times are in ticks, in seconds when suffixed with "s", or relative
+// to the previous event when prefixed with "+". Times may never go backwards.
+package level
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"hi/curve"
+	"hi/textfile"
+)
+
+// TPS is the number of simulation ticks per second, used for converting times given in seconds
+const TPS = 60
+
+// EventKind is the type of a timed event
+type EventKind uint8
+
+const (
+	Spawn EventKind = iota
+	Formation
+	Background
+	Boss
+)
+
+// Point is a position in screen coordinates
+type Point = curve.Point
+
+// Event is a single timed line in a level file
+type Event struct {
+	Line  int
+	Tick  uint64
+	Kind  EventKind
+	Enemy string     // Spawn, Formation: enemy kind
+	Pos   Point      // Spawn, Formation: spawn position
+	Path  string     // Spawn, Formation: optional movement path
+	Shape string     // Formation: line, column or v
+	Count int        // Formation: number of enemies
+	Space float64    // Formation: distance between enemies
+	Color color.RGBA // Background
+	Boss  string     // Boss: boss name
+}
+
+// Level is a parsed level file
+type Level struct {
+	Filename string
+	Name     string
+	Paths    map[string]*curve.Path
+	Events   []Event // sorted by Tick
+}
+
+// Errorf returns a *textfile.Error for the given line of this level
+func (l *Level) Errorf(line int, format string, args ...any) error {
+	return textfile.Errorf(l.Filename, line, format, args...)
+}
+
+// Load reads and parses a level file
+func Load(filename string) (*Level, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads a level from r. The filename is only used for error messages.
+func Parse(r io.Reader, filename string) (*Level, error) {
+	l := &Level{Filename: filename, Paths: make(map[string]*curve.Path)}
+	scanner := bufio.NewScanner(r)
+	var tick uint64
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") && !isColor(f) {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if !isTime(fields[0]) {
+			if err := l.directive(line, fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		t, err := parseTime(fields[0], tick)
+		if err != nil {
+			return nil, l.Errorf(line, "%v", err)
+		}
+		if t < tick {
+			return nil, l.Errorf(line, "time %s is before the previous event at tick %d", fields[0], tick)
+		}
+		tick = t
+		if len(fields) < 2 {
+			return nil, l.Errorf(line, "missing event after time")
+		}
+		e, err := l.event(line, fields[1], fields[2:])
+		if err != nil {
+			return nil, err
+		}
+		e.Line, e.Tick = line, tick
+		l.Events = append(l.Events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, e := range l.Events {
+		if e.Path != "" && l.Paths[e.Path] == nil {
+			return nil, l.Errorf(e.Line, "unknown path %q", e.Path)
+		}
+	}
+	return l, nil
+}
+
+func (l *Level) directive(line int, fields []string) error {
+	switch fields[0] {
+	case "name":
+		l.Name = strings.Join(fields[1:], " ")
+	case "path":
+		if len(fields) < 2 {
+			return l.Errorf(line, "usage: path <name> [line|bezier|spline] <x,y> <x,y> ...")
+		}
+		if l.Paths[fields[1]] != nil {
+			return l.Errorf(line, "path %q is already defined", fields[1])
+		}
+		p, err := parsePath(fields[2:])
+		if err != nil {
+			return l.Errorf(line, "path %s: %v", fields[1], err)
+		}
+		l.Paths[fields[1]] = p
+	default:
+		return l.Errorf(line, "unknown directive %q", fields[0])
+	}
+	return nil
+}
+
+func (l *Level) event(line int, cmd string, fields []string) (Event, error) {
+	var e Event
+	args, opts, err := splitOptions(fields)
+	if err != nil {
+		return e, l.Errorf(line, "%v", err)
+	}
+	switch cmd {
+	case "spawn":
+		e.Kind = Spawn
+		if len(args) != 3 {
+			return e, l.Errorf(line, "usage: spawn <enemy> <x> <y> [path=name]")
+		}
+		e.Enemy = args[0]
+		if e.Pos, err = parsePoint(args[1], args[2]); err != nil {
+			return e, l.Errorf(line, "%v", err)
+		}
+		e.Path = opts["path"]
+		delete(opts, "path")
+	case "formation":
+		e.Kind = Formation
+		if len(args) != 5 {
+			return e, l.Errorf(line, "usage: formation <line|column|v> <count> <enemy> <x> <y> [spacing=n] [path=name]")
+		}
+		e.Shape = args[0]
+		if e.Shape != "line" && e.Shape != "column" && e.Shape != "v" {
+			return e, l.Errorf(line, "unknown formation %q", e.Shape)
+		}
+		if e.Count, err = strconv.Atoi(args[1]); err != nil || e.Count < 1 {
+			return e, l.Errorf(line, "invalid count %q", args[1])
+		}
+		e.Enemy = args[2]
+		if e.Pos, err = parsePoint(args[3], args[4]); err != nil {
+			return e, l.Errorf(line, "%v", err)
+		}
+		e.Space = 24
+		if s, ok := opts["spacing"]; ok {
+			if e.Space, err = strconv.ParseFloat(s, 64); err != nil {
+				return e, l.Errorf(line, "invalid spacing %q", s)
+			}
+			delete(opts, "spacing")
+		}
+		e.Path = opts["path"]
+		delete(opts, "path")
+	case "background":
+		e.Kind = Background
+		if len(args) != 1 || !isColor(args[0]) {
+			return e, l.Errorf(line, "usage: background <#rrggbb>")
+		}
+		c, _ := strconv.ParseUint(args[0][1:], 16, 32)
+		e.Color = color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xff}
+	case "boss":
+		e.Kind = Boss
+		if len(args) != 1 {
+			return e, l.Errorf(line, "usage: boss <name>")
+		}
+		e.Boss = args[0]
+	default:
+		return e, l.Errorf(line, "unknown event %q", cmd)
+	}
+	// the options that are left are unknown, the first one on the line is reported
+	for _, f := range fields {
+		key, _, _ := strings.Cut(f, "=")
+		if _, ok := opts[key]; ok {
+			return e, l.Errorf(line, "unknown option %q for %s", key, cmd)
+		}
+	}
+	return e, nil
+}
+
+// Positions returns the spawn positions of the enemies in a Spawn or Formation event
+func (e *Event) Positions() []Point {
+	if e.Kind == Spawn {
+		return []Point{e.Pos}
+	}
+	ps := make([]Point, e.Count)
+	for i := range ps {
+		// offset from the middle of the formation
+		o := float64(i) - float64(e.Count-1)/2
+		switch e.Shape {
+		case "line":
+			ps[i] = Point{X: e.Pos.X + o*e.Space, Y: e.Pos.Y}
+		case "column":
+			ps[i] = Point{X: e.Pos.X, Y: e.Pos.Y - float64(i)*e.Space}
+		case "v":
+			ps[i] = Point{X: e.Pos.X + o*e.Space, Y: e.Pos.Y - math.Abs(o)*e.Space}
+		}
+	}
+	return ps
+}
+
+// splitOptions separates key=value options from positional arguments
+func splitOptions(fields []string) ([]string, map[string]string, error) {
+	var args []string
+	opts := make(map[string]string)
+	for _, f := range fields {
+		key, value, found := strings.Cut(f, "=")
+		if !found {
+			args = append(args, f)
+			continue
+		}
+		if _, dup := opts[key]; dup {
+			return nil, nil, fmt.Errorf("option %q given twice", key)
+		}
+		opts[key] = value
+	}
+	return args, opts, nil
+}
+
+func isTime(s string) bool {
+	return s != "" && (s[0] == '+' || (s[0] >= '0' && s[0] <= '9'))
+}
+
+func isColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(s[1:], 16, 32)
+	return err == nil
+}
+
+// parseTime returns the tick for "120", "2.5s" or "+30", where relative
+// times are added to prev
+func parseTime(s string, prev uint64) (uint64, error) {
+	rel := strings.HasPrefix(s, "+")
+	num := strings.TrimPrefix(s, "+")
+	scale := 1.0
+	if strings.HasSuffix(num, "s") {
+		num, scale = strings.TrimSuffix(num, "s"), TPS
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	t := uint64(f*scale + 0.5)
+	if rel {
+		t += prev
+	}
+	return t, nil
+}
+
+func parsePoint(xs, ys string) (Point, error) {
+	x, err := strconv.ParseFloat(xs, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid x coordinate %q", xs)
+	}
+	y, err := strconv.ParseFloat(ys, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid y coordinate %q", ys)
+	}
+	return Point{X: x, Y: y}, nil
+}
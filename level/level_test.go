@@ -0,0 +1,124 @@
+package level
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+func TestParse(t *testing.T) {
+	src := `# a comment
+name Test Level
+path dive 0,0 0,80
+path loop spline 0,0 0,100 80,140 120,60
+
+0    background #000010 # a comment after a color
+2s   spawn grunt 160 -16 path=dive
++30  formation v 3 grunt 160 -16 spacing=20 path=loop
++0   formation line 2 weaver 100 -16
+2.5s boss warden
+`
+	l, err := Parse(strings.NewReader(src), "test.lvl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Name != "Test Level" {
+		t.Errorf("name is %q", l.Name)
+	}
+	if len(l.Paths) != 2 || l.Paths["dive"] == nil || l.Paths["loop"] == nil {
+		t.Errorf("paths are %v", l.Paths)
+	}
+	want := []Event{
+		{Line: 6, Tick: 0, Kind: Background, Color: color.RGBA{0, 0, 0x10, 0xff}},
+		{Line: 7, Tick: 120, Kind: Spawn, Enemy: "grunt", Pos: Point{X: 160, Y: -16}, Path: "dive"},
+		{Line: 8, Tick: 150, Kind: Formation, Enemy: "grunt", Pos: Point{X: 160, Y: -16}, Path: "loop", Shape: "v", Count: 3, Space: 20},
+		{Line: 9, Tick: 150, Kind: Formation, Enemy: "weaver", Pos: Point{X: 100, Y: -16}, Shape: "line", Count: 2, Space: 24},
+		{Line: 10, Tick: 150, Kind: Boss, Boss: "warden"},
+	}
+	if len(l.Events) != len(want) {
+		t.Fatalf("%d events, want %d", len(l.Events), len(want))
+	}
+	for i, e := range want {
+		if l.Events[i] != e {
+			t.Errorf("event %d is %+v, want %+v", i, l.Events[i], e)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"name x\nfoo bar", 2, `unknown directive "foo"`},
+		{"10 spawn grunt 0 0\n5 spawn grunt 0 0", 2, "time 5 is before the previous event at tick 10"},
+		{"10", 1, "missing event after time"},
+		{"1x spawn grunt 0 0", 1, `invalid time "1x"`},
+		{"\n\n0 spawn grunt 0", 3, "usage: spawn <enemy> <x> <y> [path=name]"},
+		{"0 spawn grunt a 0", 1, `invalid x coordinate "a"`},
+		{"0 formation ring 3 grunt 0 0", 1, `unknown formation "ring"`},
+		{"0 formation line 0 grunt 0 0", 1, `invalid count "0"`},
+		{"0 formation line 3 grunt 0 0 spacing=x", 1, `invalid spacing "x"`},
+		{"0 spawn grunt 0 0 speed=2", 1, `unknown option "speed" for spawn`},
+		{"0 formation line 3 grunt 0 0 hp=3 spacing=10 speed=2", 1, `unknown option "hp" for formation`},
+		{"0 spawn grunt 0 0 path=a path=b", 1, `option "path" given twice`},
+		{"0 background red", 1, "usage: background <#rrggbb>"},
+		{"0 dance", 1, `unknown event "dance"`},
+		{"path a 0,0 1,1\npath a 0,0 1,1", 2, `path "a" is already defined`},
+		{"path a 0,0 1", 1, `path a: invalid point "1", expected x,y`},
+		{"# first\n0 spawn grunt 0 0 path=nowhere", 2, `unknown path "nowhere"`},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.lvl")
+		textfile.Check(t, err, "bad.lvl", tt.line, tt.msg)
+	}
+}
+
+// TestUnknownOptions checks that the first of several unknown options is
+// reported, every time
+func TestUnknownOptions(t *testing.T) {
+	for range 20 {
+		_, err := Parse(strings.NewReader("0 spawn grunt 0 0 speed=2 hp=3 angle=90 size=2"), "bad.lvl")
+		textfile.Check(t, err, "bad.lvl", 1, `unknown option "speed" for spawn`)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	for _, tt := range []struct {
+		e    Event
+		want []Point
+	}{
+		{Event{Kind: Spawn, Pos: Point{X: 1, Y: 2}}, []Point{{X: 1, Y: 2}}},
+		{Event{Kind: Formation, Shape: "line", Count: 3, Space: 10, Pos: Point{X: 50}}, []Point{{X: 40}, {X: 50}, {X: 60}}},
+		{Event{Kind: Formation, Shape: "column", Count: 2, Space: 10, Pos: Point{X: 50}}, []Point{{X: 50}, {X: 50, Y: -10}}},
+		{Event{Kind: Formation, Shape: "v", Count: 3, Space: 10, Pos: Point{X: 50}}, []Point{{X: 40, Y: -10}, {X: 50}, {X: 60, Y: -10}}},
+	} {
+		got := tt.e.Positions()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.e.Shape, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.e.Shape, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestLevels loads the levels that ship with the game
+func TestLevels(t *testing.T) {
+	filenames, err := filepath.Glob("../levels/*.lvl")
+	if err != nil || len(filenames) == 0 {
+		t.Fatalf("no levels: %v", err)
+	}
+	for _, filename := range filenames {
+		if _, err := Load(filename); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package level
+
+import (
+	"fmt"
+	"strings"
+
+	"hi/curve"
+)
+
+// parsePath parses the arguments of a path directive, which are an optional
+// curve type (line, bezier or spline, line by default) followed by the points
+func parsePath(fields []string) (*curve.Path, error) {
+	kind := "line"
+	if len(fields) > 0 && !strings.Contains(fields[0], ",") {
+		kind, fields = fields[0], fields[1:]
+	}
+	var points []Point
+	for _, f := range fields {
+		xs, ys, found := strings.Cut(f, ",")
+		if !found {
+			return nil, fmt.Errorf("invalid point %q, expected x,y", f)
+		}
+		pt, err := parsePoint(xs, ys)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+	}
+	c, err := curve.New(kind, points)
+	if err != nil {
+		return nil, err
+	}
+	return curve.NewPath(c), nil
+}
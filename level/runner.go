@@ -0,0 +1,31 @@
+package level
+
+// Runner keeps track of which events of a level have been triggered.
+// It is a plain value, so that copying a Runner also copies its progress.
+type Runner struct {
+	Level *Level
+	next  int
+}
+
+// NewRunner returns a runner that starts at the beginning of the level
+func NewRunner(l *Level) Runner {
+	return Runner{Level: l}
+}
+
+// Due returns the events that should be triggered at the given tick,
+// and every event before it that has not been returned yet
+func (r *Runner) Due(tick uint64) []Event {
+	if r.Level == nil {
+		return nil
+	}
+	start := r.next
+	for r.next < len(r.Level.Events) && r.Level.Events[r.next].Tick <= tick {
+		r.next++
+	}
+	return r.Level.Events[start:r.next]
+}
+
+// Done returns true when all events have been triggered
+func (r *Runner) Done() bool {
+	return r.Level == nil || r.next >= len(r.Level.Events)
+}
@@ -0,0 +1,310 @@
+// Package locale translates the text that the game shows. Every language
+// has a file named after its code, like lang/de.txt, with a message per
+// line: the message ID, and the text as a fmt format.
+//
+//	# German
+//	language Deutsch
+//	plural one-other
+//
+//	menu.resume        Weiter
+//	attract.press      %s DRÜCKEN ZUM SPIELEN
+//	stats.kills[one]   %d Abschuss
+//	stats.kills[other] %d Abschüsse
+//
+// "language" is the name of the language, in the language, and "plural" is
+// the rule that picks the form of messages that depend on a number:
+//
+//	one-other  one for 1, and other for everything else, like English
+//	other      the same form for every number, like Japanese
+//
+// A message with a form in brackets must have all the forms of the rule,
+// and gets the number as its first argument.
+//
+// The text is drawn with a bitmap font, so every character of it must be in
+// the font, see Catalog.Glyphs. Messages with IDs that start with "window."
+// are shown by the operating system instead, and may use any character.
+package locale
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"hi/textfile"
+)
+
+// WINDOW is the start of the IDs of messages that the operating system shows
+const WINDOW = "window."
+
+// Rules are the plural rules, which return the form to use for a number
+var Rules = map[string]func(n int) string{
+	"one-other": func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	"other": func(int) string { return "other" },
+}
+
+// forms are the forms that each rule can return
+var forms = map[string][]string{
+	"one-other": {"one", "other"},
+	"other":     {"other"},
+}
+
+// Message is the text of a message, with one text per form if it is plural
+type Message struct {
+	ID    string
+	Text  string
+	Forms map[string]string // nil if the message is not plural
+	Line  int
+}
+
+// Catalog is the messages of one language
+type Catalog struct {
+	Code     string // like "de", from the filename
+	Name     string // the name of the language, in the language
+	Rule     string // the plural rule
+	Messages map[string]*Message
+	Filename string
+}
+
+// Text returns the message with the given ID, formatted with args. Missing
+// messages come out as their ID, so that they are easy to spot.
+func (c *Catalog) Text(id string, args ...any) string {
+	m := c.Messages[id]
+	if m == nil || m.Forms != nil {
+		return id
+	}
+	return fmt.Sprintf(m.Text, args...)
+}
+
+// Plural returns the form of the message for n, formatted with n and args
+func (c *Catalog) Plural(id string, n int, args ...any) string {
+	m := c.Messages[id]
+	if m == nil || m.Forms == nil {
+		return id
+	}
+	return fmt.Sprintf(m.Forms[Rules[c.Rule](n)], append([]any{n}, args...)...)
+}
+
+// Has returns true if the catalog has the message
+func (c *Catalog) Has(id string) bool {
+	return c.Messages[id] != nil
+}
+
+// LoadDir loads every .txt file in dir, by language code
+func LoadDir(dir string) (map[string]*Catalog, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	catalogs := make(map[string]*Catalog)
+	for _, filename := range filenames {
+		c, err := Load(filename)
+		if err != nil {
+			return nil, err
+		}
+		catalogs[c.Code] = c
+	}
+	return catalogs, nil
+}
+
+// Load reads and parses a language file
+func Load(filename string) (*Catalog, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads the messages of a language from r. The language code is
+// the filename without the extension.
+func Parse(r io.Reader, filename string) (*Catalog, error) {
+	c := &Catalog{
+		Code:     strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)),
+		Messages: make(map[string]*Message),
+		Filename: filename,
+	}
+	line := 0
+	errorf := func(format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, text, _ := strings.Cut(text, " ")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, errorf("%s has no text", id)
+		}
+		switch id {
+		case "language":
+			c.Name = text
+			continue
+		case "plural":
+			if Rules[text] == nil {
+				return nil, errorf("unknown plural rule %q", text)
+			}
+			c.Rule = text
+			continue
+		}
+		form := ""
+		if i := strings.IndexByte(id, '['); i >= 0 && strings.HasSuffix(id, "]") {
+			id, form = id[:i], id[i+1:len(id)-1]
+		}
+		m := c.Messages[id]
+		switch {
+		case m == nil:
+			m = &Message{ID: id, Line: line}
+			c.Messages[id] = m
+		case form == "" || m.Forms == nil:
+			return nil, errorf("%s is already on line %d", id, m.Line)
+		}
+		if form == "" {
+			m.Text = text
+			continue
+		}
+		if c.Rule == "" {
+			return nil, errorf("%s has a plural form, but there is no plural rule before it", id)
+		}
+		if !slices.Contains(forms[c.Rule], form) {
+			return nil, errorf("%s has form %q, but the forms of %s are %s", id, form, c.Rule, strings.Join(forms[c.Rule], ", "))
+		}
+		if m.Forms == nil {
+			m.Forms = make(map[string]string)
+		}
+		if _, ok := m.Forms[form]; ok {
+			return nil, errorf("%s[%s] is already there", id, form)
+		}
+		m.Forms[form] = text
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if c.Name == "" {
+		return nil, textfile.Errorf(filename, line, "the file does not say which language it is")
+	}
+	for _, id := range c.IDs() {
+		m := c.Messages[id]
+		for _, form := range forms[c.Rule] {
+			if _, ok := m.Forms[form]; m.Forms != nil && !ok {
+				return nil, textfile.Errorf(filename, m.Line, "%s is missing the %s form", m.ID, form)
+			}
+		}
+	}
+	return c, nil
+}
+
+// verbs matches the fmt verbs of a text
+var verbs = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// arguments returns the verbs of a text that take an argument, in order
+func arguments(text string) []string {
+	var args []string
+	for _, v := range verbs.FindAllString(text, -1) {
+		if v != "%%" {
+			args = append(args, v[len(v)-1:])
+		}
+	}
+	return args
+}
+
+// Check compares the catalog against the one it was translated from, and
+// returns an error for every message that is missing, extra, or takes
+// different arguments
+func (c *Catalog) Check(base *Catalog) []error {
+	var errs []error
+	for _, id := range c.IDs() {
+		m, b := c.Messages[id], base.Messages[id]
+		if b == nil {
+			errs = append(errs, textfile.Errorf(c.Filename, m.Line, "%s is not in %s", id, base.Filename))
+			continue
+		}
+		if (m.Forms == nil) != (b.Forms == nil) {
+			errs = append(errs, textfile.Errorf(c.Filename, m.Line, "%s must be plural if and only if it is in %s", id, base.Filename))
+			continue
+		}
+		want := arguments(b.text())
+		for _, text := range m.texts() {
+			if got := arguments(text); !slices.Equal(got, want) {
+				errs = append(errs, textfile.Errorf(c.Filename, m.Line, "%s takes %v, but it is %v in %s", id, got, want, base.Filename))
+				break
+			}
+		}
+	}
+	for _, id := range base.IDs() {
+		if c.Messages[id] == nil {
+			errs = append(errs, fmt.Errorf("%s: %s is missing", c.Filename, id))
+		}
+	}
+	return errs
+}
+
+// Glyphs returns an error for every message that has a character that the
+// font does not have
+func (c *Catalog) Glyphs(has func(r rune) bool) []error {
+	var errs []error
+	for _, id := range c.IDs() {
+		if strings.HasPrefix(id, WINDOW) {
+			continue
+		}
+		m := c.Messages[id]
+		var missing []string
+		for _, text := range m.texts() {
+			for _, r := range text {
+				if !has(r) && !slices.Contains(missing, string(r)) {
+					missing = append(missing, string(r))
+				}
+			}
+		}
+		if len(missing) > 0 {
+			errs = append(errs, textfile.Errorf(c.Filename, m.Line, "%s has characters that are not in the font: %s", id, strings.Join(missing, " ")))
+		}
+	}
+	return errs
+}
+
+// IDs returns the message IDs, in the order they are in the file
+func (c *Catalog) IDs() []string {
+	ids := make([]string, 0, len(c.Messages))
+	for id := range c.Messages {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return c.Messages[ids[i]].Line < c.Messages[ids[j]].Line })
+	return ids
+}
+
+// texts returns the text of a message, or the text of every form
+func (m *Message) texts() []string {
+	if m.Forms == nil {
+		return []string{m.Text}
+	}
+	var texts []string
+	for _, text := range m.Forms {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+// text returns the text of a message, or of the form for other numbers
+func (m *Message) text() string {
+	if m.Forms == nil {
+		return m.Text
+	}
+	return m.Forms["other"]
+}
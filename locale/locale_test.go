@@ -0,0 +1,163 @@
+package locale
+
+import (
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+// latin1 is what the font of the game has, the printable characters of Latin-1
+func latin1(r rune) bool {
+	return r >= 0x20 && r < 0x7f || r >= 0xa0 && r <= 0xff
+}
+
+func parse(t *testing.T, src string) *Catalog {
+	t.Helper()
+	c, err := Parse(strings.NewReader(src), "lang/xx.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+const english = `# English
+language English
+plural one-other
+
+menu.resume     Resume
+attract.press   PRESS %s TO PLAY
+stats.kills[one]   %d kill
+stats.kills[other] %d kills
+stats.accuracy  %d%% accuracy
+`
+
+func TestParse(t *testing.T) {
+	c := parse(t, english)
+	if c.Code != "xx" || c.Name != "English" || c.Rule != "one-other" || c.Filename != "lang/xx.txt" {
+		t.Errorf("the catalog is %s %q with %s", c.Code, c.Name, c.Rule)
+	}
+	if got := strings.Join(c.IDs(), " "); got != "menu.resume attract.press stats.kills stats.accuracy" {
+		t.Errorf("the IDs are %s", got)
+	}
+	if m := c.Messages["stats.kills"]; m.Line != 7 || len(m.Forms) != 2 {
+		t.Errorf("stats.kills is %+v", m)
+	}
+	for _, tt := range []struct{ got, want string }{
+		{c.Text("menu.resume"), "Resume"},
+		{c.Text("attract.press", "ENTER"), "PRESS ENTER TO PLAY"},
+		{c.Text("stats.accuracy", 50), "50% accuracy"},
+		{c.Text("menu.quit"), "menu.quit"},
+		{c.Text("stats.kills"), "stats.kills"},
+		{c.Plural("stats.kills", 1), "1 kill"},
+		{c.Plural("stats.kills", 0), "0 kills"},
+		{c.Plural("stats.kills", 12), "12 kills"},
+		{c.Plural("menu.resume", 2), "menu.resume"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+	ja := parse(t, "language 日本語\nplural other\nstats.kills[other] 撃破 %d\n")
+	if got := ja.Plural("stats.kills", 1); got != "撃破 1" {
+		t.Errorf("got %q with the other rule", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const head = "language X\nplural one-other\n"
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"plural one-other\n", 1, "the file does not say which language it is"},
+		{"language\n", 1, "language has no text"},
+		{"language X\nplural dual\n", 2, `unknown plural rule "dual"`},
+		{head + "a A\n\na B\n", 5, "a is already on line 3"},
+		{head + "a[one] A\na B\n", 4, "a is already on line 3"},
+		{"language X\na[one] A\n", 2, "a has a plural form, but there is no plural rule before it"},
+		{head + "a[few] A\n", 3, `a has form "few", but the forms of one-other are one, other`},
+		{head + "a[one] A\na[one] B\n", 4, "a[one] is already there"},
+		{head + "# a\na[one] A\n", 4, "a is missing the other form"},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "xx.txt")
+		textfile.Check(t, err, "xx.txt", tt.line, tt.msg)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	base := parse(t, english)
+	base.Filename = "lang/en.txt"
+	c := parse(t, `language Test
+plural other
+menu.resume        Weiter %d
+attract.press      %s
+stats.kills[other] %d
+menu.quit          Beenden
+`)
+	var got []string
+	for _, err := range c.Check(base) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"lang/xx.txt:3: menu.resume takes [d], but it is [] in lang/en.txt",
+		"lang/xx.txt:6: menu.quit is not in lang/en.txt",
+		"lang/xx.txt: stats.accuracy is missing",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	plural := parse(t, "language Test\nplural other\nmenu.resume[other] Weiter\n")
+	if errs := plural.Check(base); len(errs) == 0 || errs[0].Error() != "lang/xx.txt:3: menu.resume must be plural if and only if it is in lang/en.txt" {
+		t.Errorf("got %v for a plural message that is not plural in the base", errs)
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	c := parse(t, `language 日本語
+plural other
+window.title  ハイ
+menu.resume   再開
+menu.quit     Beenden ÄÖÜ
+stats.kills[other] 撃破 %d
+`)
+	var got []string
+	for _, err := range c.Glyphs(latin1) {
+		got = append(got, err.Error())
+	}
+	// the name of the language and window. messages are not drawn with the font
+	want := []string{
+		"lang/xx.txt:4: menu.resume has characters that are not in the font: 再 開",
+		"lang/xx.txt:6: stats.kills has characters that are not in the font: 撃 破",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestLoadDir loads the languages that ship with the game, and checks them
+// against English
+func TestLoadDir(t *testing.T) {
+	catalogs, err := LoadDir("../lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := catalogs["en"]
+	if base == nil {
+		t.Fatal("there is no en.txt")
+	}
+	for code, c := range catalogs {
+		if c.Code != code {
+			t.Errorf("%s has the code %s", c.Filename, c.Code)
+		}
+		if c != base {
+			for _, err := range c.Check(base) {
+				t.Error(err)
+			}
+		}
+		for _, err := range c.Glyphs(latin1) {
+			t.Error(err)
+		}
+	}
+}
@@ -1,105 +1,417 @@
 package main
 
 import (
-	"errors"
+	"flag"
 	"fmt"
+	"image/color"
+	"math/rand"
 	"os"
+	"strings"
+
+	"hi/replay"
+	"hi/sim"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
-var images map[uint64]*ebiten.Image
+var (
+	images map[uint64]*ebiten.Image
+	postFX *PostFX
+)
 
 const (
 	SHIP = iota
 	BULLET
+	ENEMY
+	BOSS
+	TURRET
+	ORB
+	WEAPON
+	SHIELD
+	SPEED
+	BOMB
+	LIFE
 )
 
+// pickupImages are the image IDs for each sim.Item
+var pickupImages = [sim.NUM_ITEMS]uint64{WEAPON, SHIELD, SPEED, BOMB, LIFE}
+
+var imageFiles = map[uint64]string{
+	SHIP:   "img/ship.png",
+	BULLET: "img/bullet.png",
+	ENEMY:  "img/enemy.png",
+	BOSS:   "img/boss.png",
+	TURRET: "img/turret.png",
+	ORB:    "img/orb.png",
+	WEAPON: "img/weapon.png",
+	SHIELD: "img/shield.png",
+	SPEED:  "img/speed.png",
+	BOMB:   "img/bomb.png",
+	LIFE:   "img/life.png",
+}
+
 const (
-	W = 320
-	H = 240
+	W = sim.W
+	H = sim.H
+)
+
+var (
+	world *sim.World
+	seats = NewSeats()
+
+	// playback is the replay that is being played back, if any
+	playback *replay.Player
 
-	SHIP_W = 16
-	SHIP_H = 16
+	// recording is the game that is being recorded, if any
+	recording *replay.Replay
 
-	BULLET_W = 2
-	BULLET_H = 2
+	// shake is how much the screen shakes, from 0 to 1. It fades out by itself.
+	shake float64
+
+	// bus hands the events of every tick to the sounds and effects
+	bus = &sim.Bus{}
 )
 
-type Vec2 struct {
-	x float64
-	y float64
-}
+// MAX_SHAKE is how far the screen moves when shaking the most, in pixels
+const MAX_SHAKE = 4
 
-type Bullet struct {
-	x    float64
-	y    float64
-	vx   float64
-	vy   float64
-	life uint64
+func init() {
+	// the screen shakes when a player loses a life or sets off a bomb
+	sim.On(bus, func(e sim.PlayerHit) {
+		if !e.Shield {
+			kick()
+		}
+	})
+	sim.On(bus, func(sim.BombUsed) { kick() })
 }
 
-var (
-	ship    = Vec2{(W - SHIP_W) / 2, (H - SHIP_H) / 2}
-	bullets []Bullet
-)
+// kick shakes the screen and splits the colors for a moment, unless
+// motion is reduced
+func kick() {
+	if settings.Access.ReducedMotion {
+		return
+	}
+	if postFX != nil {
+		postFX.Kick("chromatic", 1)
+	}
+	shake = 1
+}
 
 // Game implements the ebiten Game interface
 type Game struct{}
 
 // Update proceeds the game state and is called every tick (1/60 s by default)
 func (g *Game) Update() error {
-	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		ship.x--
-	} else if ebiten.IsKeyPressed(ebiten.KeyRight) {
-		ship.x++
+	// the game speed may have changed, or an online game started
+	if tps := gameTPS(); tps != ebiten.TPS() {
+		ebiten.SetTPS(tps)
+	}
+	postFX.Update()
+	// the sounds are timed in Updates, which the game speed slows down
+	mix.TPS = ebiten.TPS()
+	mix.Update()
+	updateToasts()
+	if shake *= 0.85; shake < 0.05 {
+		shake = 0
+	}
+
+	// Esc opens the options menu, which pauses the game unless it is online,
+	// and the backtick key opens the console
+	switch {
+	case console.Open:
+		console.Update()
+	case options.Open:
+		if !options.Update() {
+			if session != nil {
+				session.Close()
+			}
+			return ebiten.Termination
+		}
+		if session == nil {
+			return nil
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		options.Open = true
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackquote):
+		console.Open = true
+	}
+
+	// F5, F6, F7 and F8 toggle the first four post-processing effects
+	for i, key := range []ebiten.Key{ebiten.KeyF5, ebiten.KeyF6, ebiten.KeyF7, ebiten.KeyF8} {
+		if i < len(postFX.Effects) && inpututil.IsKeyJustPressed(key) {
+			name := postFX.Effects[i].Name
+			settings.Video.Effects[name] = !settings.Video.Effects[name]
+			settings.Apply()
+			saveSettings()
+		}
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyUp) {
-		ship.y--
-	} else if ebiten.IsKeyPressed(ebiten.KeyDown) {
-		ship.y++
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		debug = !debug
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		b := Bullet{ship.x, ship.y, 0, -1, 100}
-		bullets = append(bullets, b)
+
+	// F12 saves a screenshot, F10 starts and stops recording a GIF
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		capture.Screenshot()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
-		return errors.New("quit")
+	if inpututil.IsKeyJustPressed(ebiten.KeyF10) {
+		capture.ToggleGIF()
 	}
 
-	// Update bullets
-	aliveBullets := make([]Bullet, 0, len(bullets))
-	for i := 0; i < len(bullets); i++ {
-		bullets[i].x += bullets[i].vx
-		bullets[i].y += bullets[i].vy
-		if bullets[i].life > 1 {
-			bullets[i].life--
-			aliveBullets = append(aliveBullets, bullets[i])
+	// online games can not be paused or rewound
+	ticks := 1
+	if session == nil {
+		if !console.Open {
+			clock.Update()
+		}
+		ticks = clock.Ticks()
+		// the players are read once per frame, and the latch keeps what
+		// they pressed for the ticks
+		if attract == nil && playback == nil {
+			latch.Add(localInputs())
+		}
+	}
+	for i := 0; i < ticks; i++ {
+		stepped, err := step()
+		if err != nil {
+			return err
+		}
+		// online, the events come from the session, which only hands out
+		// the ones that a rollback can not change any more
+		if session != nil {
+			bus.Publish(session.Events())
+		} else if stepped {
+			bus.Publish(world.Events)
+		}
+		if !stepped {
+			continue
+		}
+		updateAchievements()
+		if session == nil {
+			history.Save(world)
 		}
 	}
-	bullets = aliveBullets
 
 	return nil
 }
 
+// step steps the world by one tick, with the input from wherever it comes
+// from. It returns false if the world did not advance, like when an online
+// game waits for the other player.
+func step() (bool, error) {
+	switch {
+	case session != nil:
+		return stepNetplay()
+	case attract != nil:
+		return true, stepAttract()
+	case playback != nil:
+		more, err := playback.Step(world)
+		if err != nil {
+			return false, err
+		}
+		if !more {
+			return false, ebiten.Termination
+		}
+	case recording != nil:
+		recording.Add(world, latch.Take())
+	default:
+		world.Step(latch.Take())
+	}
+	return true, nil
+}
+
+// latch keeps the inputs of the players at this computer for the ticks,
+// which do not line up with the frames under the time control
+var latch = &sim.Latch{}
+
+// localInputs returns the inputs of the players at this computer, which are
+// held back while the console is open
+func localInputs() []sim.Input {
+	if console.Open {
+		return nil
+	}
+	return seats.Inputs(world)
+}
+
 // Draw is the render function and is called every frame (1/60s by default)
 func (g *Game) Draw(screen *ebiten.Image) {
-	op := &ebiten.DrawImageOptions{}
-	//op.GeoM.Reset()
-	op.GeoM.Translate(ship.x, ship.y)
-	screen.DrawImage(images[SHIP], op)
+	// with a palette, everything is drawn offscreen and then through it,
+	// which is cleared every frame like the screen
+	out, pal := screen, currentPalette()
+	if pal != nil {
+		screen = paletteScene
+		screen.Clear()
+	}
+
+	// the frame is drawn offscreen first for post-processing and screen shake
+	offscreen := postFX.Active() || shake*settings.Gameplay.Shake > 0
+	target := screen
+	if offscreen {
+		target = postFX.Scene()
+	}
+
+	target.Fill(world.Background)
+
+	for i := 0; i < len(world.Enemies); i++ {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(world.Enemies[i].X, world.Enemies[i].Y)
+		target.DrawImage(images[ENEMY], op)
+	}
+
+	if world.Boss != nil {
+		drawBoss(target, world.Boss)
+	}
 
-	for i := 0; i < len(bullets); i++ {
+	for _, p := range world.Pickups {
+		// pickups blink when they are about to disappear, or fade with reduced motion
+		op := &ebiten.DrawImageOptions{}
+		if p.Age > sim.PICKUP_LIFE-120 {
+			if settings.Access.ReducedMotion {
+				op.ColorScale.ScaleAlpha(0.5)
+			} else if (p.Age/4)%2 == 0 {
+				continue
+			}
+		}
+		op.GeoM.Translate(p.X, p.Y)
+		target.DrawImage(images[pickupImages[p.Item]], op)
+	}
+
+	for i := range world.Players {
+		drawPlayer(target, i, &world.Players[i])
+	}
+
+	// the outlines go under all enemy bullets, so that they do not cover each other
+	if settings.Access.HighContrast {
+		for i := range world.Bullets {
+			if world.Bullets[i].Hostile {
+				drawOutline(target, &world.Bullets[i])
+			}
+		}
+	}
+
+	for i := 0; i < len(world.Bullets); i++ {
+		if world.Bullets[i].Hostile {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(world.Bullets[i].X, world.Bullets[i].Y)
+			target.DrawImage(images[ORB], op)
+			continue
+		}
 		op := &ebiten.DrawImageOptions{}
 		//op.GeoM.Reset()
-		op.GeoM.Translate(bullets[i].x+SHIP_W/2-1, bullets[i].y)
-		aliveRatio := float32(bullets[i].life) / float32(100.0)
+		op.GeoM.Translate(world.Bullets[i].X, world.Bullets[i].Y)
+		op.ColorScale.ScaleWithColor(playerColors[world.Bullets[i].Owner])
+		aliveRatio := float32(world.Bullets[i].Life) / float32(sim.BULLET_LIFE)
 		op.ColorScale.ScaleAlpha(aliveRatio)
-		screen.DrawImage(images[BULLET], op)
+		target.DrawImage(images[BULLET], op)
+	}
+
+	for i := 0; i < len(world.Missiles); i++ {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(2, 2)
+		op.GeoM.Translate(world.Missiles[i].X-1, world.Missiles[i].Y-1)
+		op.ColorScale.Scale(1, 0.5, 0, 1)
+		target.DrawImage(images[BULLET], op)
+	}
+
+	if world.Blast != nil {
+		drawBlast(target, world.Blast)
+	}
+
+	if offscreen {
+		amount := MAX_SHAKE * shake * settings.Gameplay.Shake
+		postFX.Apply(screen, amount*(2*rand.Float64()-1), amount*(2*rand.Float64()-1))
 	}
 
+	drawHUD(screen)
+	drawToasts(screen)
+
+	if attract != nil {
+		drawAttract(screen)
+	}
+
+	if options.Open {
+		options.Draw(screen)
+	}
+
+	// The overlay is drawn after post-processing, so that it is not distorted
+	if debug {
+		drawDebug(screen)
+	}
+
+	clock.Draw(screen)
+
+	if console.Open {
+		console.Draw(screen)
+	}
+
+	if pal != nil {
+		applyPalette(out, screen, pal)
+		screen = out
+	}
+
+	capture.Frame(screen)
+	if capture.Recording {
+		text := msg("hud.recording")
+		ebitenutil.DebugPrintAt(screen, text, W-4-textWidth(text), 4)
+	}
+}
+
+// drawPlayer draws the ship of a player, tinted in the color of the player
+func drawPlayer(target *ebiten.Image, i int, p *sim.Player) {
+	// The ship blinks while it is invulnerable, or is see-through with reduced motion
+	if !p.Joined || !settings.Access.ReducedMotion && (p.Invuln/4)%2 != 0 {
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	if settings.Access.ReducedMotion && p.Invuln > 0 {
+		op.ColorScale.ScaleAlpha(0.5)
+	}
+	op.GeoM.Translate(p.Ship.X, p.Ship.Y)
+	op.ColorScale.ScaleWithColor(playerColors[i])
+	target.DrawImage(images[SHIP], op)
+	if p.ShieldTime > 0 {
+		vector.StrokeCircle(target, float32(p.Ship.X+sim.SHIP_W/2), float32(p.Ship.Y+sim.SHIP_H/2), 11, 1, shieldColor, true)
+	}
+	// show the hitbox while focused, so that the player knows what to dodge with
+	if p.Focused {
+		x, y := float32(p.Ship.X+(sim.SHIP_W-sim.HITBOX)/2), float32(p.Ship.Y+(sim.SHIP_H-sim.HITBOX)/2)
+		vector.FillRect(target, x-1, y-1, sim.HITBOX+2, sim.HITBOX+2, focusRimColor, false)
+		vector.FillRect(target, x, y, sim.HITBOX, sim.HITBOX, focusColor, false)
+	}
+}
+
+// drawBoss draws the hull of the boss and the parts that are still alive
+func drawBoss(target *ebiten.Image, b *sim.Boss) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(b.X, b.Y)
+	if b.Hit > 0 && !settings.Access.ReducedMotion {
+		op.ColorScale.Scale(2, 2, 2, 1)
+	}
+	target.DrawImage(images[BOSS], op)
+	for _, p := range b.Parts {
+		if p.Kind.HP == 0 || p.HP <= 0 {
+			continue
+		}
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(b.X+p.Kind.X, b.Y+p.Kind.Y)
+		// turrets turn red as they take damage
+		health := float32(p.HP) / float32(p.MaxHP)
+		op.ColorScale.Scale(1, health, health, 1)
+		target.DrawImage(images[TURRET], op)
+	}
+}
+
+// drawBlast draws the expanding ring of a bomb, and a white flash when it
+// goes off, unless motion is reduced
+func drawBlast(target *ebiten.Image, b *sim.Blast) {
+	if b.Age < sim.BOMB_FLASHES && !settings.Access.ReducedMotion {
+		alpha := 1 - float32(b.Age)/sim.BOMB_FLASHES
+		vector.FillRect(target, 0, 0, W, H, color.NRGBA{0xff, 0xff, 0xff, uint8(0xc0 * alpha)}, false)
+	}
+	vector.StrokeCircle(target, float32(b.X), float32(b.Y), float32(b.Radius()), 3, blastColor, true)
 }
 
 // Layout takes the outside size (e.g., the window size) and returns the (logical) screen size.
@@ -117,31 +429,150 @@ func loadImage(filename string, imageID uint64) error {
 	return nil
 }
 
-func main() {
-	// Load resources
+// loadResources loads the images and shaders, and makes the options menu,
+// which lists the effects
+func loadResources() error {
 	images = make(map[uint64]*ebiten.Image, 0)
+	for imageID, filename := range imageFiles {
+		if err := loadImage(filename, imageID); err != nil {
+			return err
+		}
+	}
+	var err error
+	postFX, err = loadPostFX("shaders")
+	if err != nil {
+		return err
+	}
+	if err := loadLanguages(); err != nil {
+		return err
+	}
+	if err := loadPalette(); err != nil {
+		return err
+	}
+	options = newOptions()
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(command(os.Args[1], os.Args[2:]))
+	}
+
+	flag.Parse()
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *versionFlag {
+		fmt.Println("hi", version)
+		return
+	}
+	if err := validateFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	err := loadImage("img/ship.png", SHIP)
+	// a broken settings file is reported, and the defaults are used instead
+	var err error
+	settings, err = loadSettings()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+	}
+	videoFlags()
+
+	if err := loadData(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := loadAudio(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	err = loadImage("img/bullet.png", BULLET)
+	// a replay knows which level, seed and difficulty it was recorded with
+	levelFile, seed, difficulty := *levelFlag, pickSeed(), gameDifficulty()
+	if *replayFlag != "" {
+		r, err := replay.Load(*replayFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		levelFile, seed, difficulty = r.Level, r.Seed, r.Difficulty
+		playback = &replay.Player{Replay: r}
+	}
+	world, err = sim.LoadLevel(levelFile, seed, difficulty)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if *recordFlag != "" {
+		recording = replay.New(levelFile, seed, difficulty)
+		defer saveRecording()
+	}
+
+	if *headlessFlag {
+		err := runHeadless()
+		if closeErr := closeAudio(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := startNetplay(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// the demo plays until someone joins, unless there is something else to do
+	if *attractFlag && session == nil && playback == nil && recording == nil {
+		if err := startAttract(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := loadAchievements(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := loadResources(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	game := &Game{}
 
-	// Specify the window size as you like. Here, a doubled size is specified.
-	ebiten.SetWindowSize(640, 480)
-	ebiten.SetWindowTitle("hi")
+	ebiten.SetTPS(gameTPS())
+	// the window title is set here too, in the language from the settings
+	settings.Apply()
 
 	// Call ebiten.RunGame to start your game loop.
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+	capture.Wait()
+	saveProgress()
+	if closeErr := closeAudio(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		saveRecording()
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+// saveRecording writes the recorded game to the -record file
+func saveRecording() {
+	if recording == nil {
+		return
+	}
+	recording.Finish(world)
+	if err := recording.Save(*recordFlag); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	recording = nil
+}
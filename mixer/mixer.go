@@ -0,0 +1,226 @@
+// Package mixer decides which sounds play and how loud. It keeps track of
+// the voices that are playing, with a volume per category, a limit on how
+// many voices of a sound play at once, random pitch and ducking of the
+// music under loud sounds. The samples go to a Backend, which plays them.
+//
+// The mixer is updated once per tick, TPS times a second, and plays sounds
+// when it is told to, so the game only has to say what happened. Voices and
+// ducking are timed in seconds, so the tick rate can change while sounds
+// play, like when the game is slowed down.
+package mixer
+
+const (
+	// SAMPLE_RATE is the sample rate of the sounds
+	SAMPLE_RATE = 44100
+
+	// MAX_VOICES is how many voices can play at once, over all sounds
+	MAX_VOICES = 32
+
+	// DUCK_RELEASE is how much the ducking recovers per second
+	DUCK_RELEASE = 1.2
+)
+
+// Category is a group of sounds with its own volume
+type Category int
+
+const (
+	Music Category = iota
+	SFX
+	UI
+	NUM_CATEGORIES
+)
+
+var categoryNames = [NUM_CATEGORIES]string{"music", "sfx", "ui"}
+
+func (c Category) String() string {
+	return categoryNames[c]
+}
+
+// Sound is a sound that the mixer can play
+type Sound struct {
+	Name      string
+	Samples   []float32 // from -1 to 1, at SAMPLE_RATE
+	Category  Category
+	Volume    float64 // from 0 to 1
+	MaxVoices int     // how many can play at once, the oldest one stops for a new one
+	Jitter    float64 // the pitch is random, up to this much higher or lower
+	Duck      float64 // how much the music is turned down while it plays, from 0 to 1
+	Loop      bool
+}
+
+// Voice is a sound that is playing
+type Voice struct {
+	ID     int
+	Sound  *Sound
+	Pitch  float64 // playback speed, 1 is normal
+	Volume float64 // the final volume, with the category, master and ducking
+	Start  float64 // the time it started at, in seconds
+	End    float64 // the time it stops at, unless it loops
+}
+
+// Backend plays voices. The mixer tells it when voices start and stop, and
+// when their volume changes.
+type Backend interface {
+	Start(v *Voice)
+	SetVolume(v *Voice)
+	Stop(v *Voice)
+}
+
+// Mixer plays sounds through a backend
+type Mixer struct {
+	Backend Backend
+	Master  float64
+	Volumes [NUM_CATEGORIES]float64
+	Tick    uint64
+	TPS     int     // how many times per second Update is called
+	Time    float64 // seconds since the mixer was made
+
+	sounds map[string]*Sound
+	voices []*Voice // oldest first
+	nextID int
+	duck   float64
+	rand   uint32
+}
+
+// New returns a mixer that plays through backend, with everything at full
+// volume, and is updated tps times per second
+func New(backend Backend, tps int) *Mixer {
+	m := &Mixer{
+		Backend: backend,
+		Master:  1,
+		TPS:     tps,
+		sounds:  make(map[string]*Sound),
+		rand:    0x9e3779b9,
+	}
+	for i := range m.Volumes {
+		m.Volumes[i] = 1
+	}
+	return m
+}
+
+// Add adds a sound that can be played by name
+func (m *Mixer) Add(s *Sound) {
+	m.sounds[s.Name] = s
+}
+
+// Play starts a sound, and returns false if there is no sound with that name
+func (m *Mixer) Play(name string) bool {
+	s := m.sounds[name]
+	if s == nil {
+		return false
+	}
+	if s.MaxVoices > 0 && m.count(s) >= s.MaxVoices {
+		m.stop(m.oldest(s))
+	}
+	if len(m.voices) >= MAX_VOICES {
+		m.stop(m.voices[0])
+	}
+	m.nextID++
+	v := &Voice{ID: m.nextID, Sound: s, Pitch: 1, Start: m.Time}
+	if s.Jitter > 0 {
+		v.Pitch += s.Jitter * (2*m.random() - 1)
+	}
+	seconds := float64(len(s.Samples)) / SAMPLE_RATE / v.Pitch
+	v.End = m.Time + seconds
+	v.Volume = m.volume(v)
+	m.voices = append(m.voices, v)
+	m.Backend.Start(v)
+	m.duck = max(m.duck, s.Duck)
+	return true
+}
+
+// Update ends the voices that are done, lets the music come back up after
+// ducking, and applies changes to the volumes
+func (m *Mixer) Update() {
+	m.Tick++
+	m.Time += 1 / float64(m.TPS)
+	playing := m.voices[:0]
+	for _, v := range m.voices {
+		if v.Playing(m.Time) {
+			playing = append(playing, v)
+		} else {
+			m.Backend.Stop(v)
+		}
+	}
+	clear(m.voices[len(playing):])
+	m.voices = playing
+
+	duck := 0.0
+	for _, v := range m.voices {
+		duck = max(duck, v.Sound.Duck)
+	}
+	m.duck = max(duck, m.duck-DUCK_RELEASE/float64(m.TPS))
+	for _, v := range m.voices {
+		if volume := m.volume(v); volume != v.Volume {
+			v.Volume = volume
+			m.Backend.SetVolume(v)
+		}
+	}
+}
+
+// Playing returns true if the voice still plays at the given time
+func (v *Voice) Playing(time float64) bool {
+	return v.Sound.Loop || time < v.End
+}
+
+// StopAll stops every voice
+func (m *Mixer) StopAll() {
+	for _, v := range m.voices {
+		m.Backend.Stop(v)
+	}
+	clear(m.voices)
+	m.voices = m.voices[:0]
+}
+
+// Voices returns the voices that are playing, oldest first
+func (m *Mixer) Voices() []*Voice {
+	return m.voices
+}
+
+// volume returns the final volume of a voice
+func (m *Mixer) volume(v *Voice) float64 {
+	volume := v.Sound.Volume * m.Volumes[v.Sound.Category] * m.Master
+	if v.Sound.Category == Music {
+		volume *= 1 - m.duck
+	}
+	return volume
+}
+
+func (m *Mixer) count(s *Sound) int {
+	n := 0
+	for _, v := range m.voices {
+		if v.Sound == s {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Mixer) oldest(s *Sound) *Voice {
+	for _, v := range m.voices {
+		if v.Sound == s {
+			return v
+		}
+	}
+	return nil
+}
+
+func (m *Mixer) stop(v *Voice) {
+	for i, w := range m.voices {
+		if w == v {
+			m.voices = append(m.voices[:i], m.voices[i+1:]...)
+			m.Backend.Stop(v)
+			return
+		}
+	}
+}
+
+// random returns a number in [0, 1). The mixer has its own random numbers,
+// so that sounds do not change how the game plays out.
+func (m *Mixer) random() float64 {
+	// xorshift32
+	m.rand ^= m.rand << 13
+	m.rand ^= m.rand >> 17
+	m.rand ^= m.rand << 5
+	return float64(m.rand) / (1 << 32)
+}
@@ -0,0 +1,170 @@
+package mixer
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// newMixer returns a mixer that logs to out, with the sounds added and a
+// fixed random seed
+func newMixer(out io.Writer, tps int, sounds ...*Sound) *Mixer {
+	m := NewNull(out, tps)
+	m.rand = 1
+	for _, s := range sounds {
+		m.Add(s)
+	}
+	return m
+}
+
+// samples returns silence that plays for the given number of seconds
+func samples(seconds float64) []float32 {
+	return make([]float32, int(seconds*SAMPLE_RATE))
+}
+
+// ids returns the IDs of the voices that are playing, oldest first
+func ids(m *Mixer) []int {
+	var ids []int
+	for _, v := range m.Voices() {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func TestMaxVoices(t *testing.T) {
+	var out bytes.Buffer
+	shoot := &Sound{Name: "shoot", Samples: samples(1), Category: SFX, Volume: 1, MaxVoices: 2}
+	hit := &Sound{Name: "hit", Samples: samples(1), Category: SFX, Volume: 1, MaxVoices: 2}
+	m := newMixer(&out, 60, shoot, hit)
+	for _, name := range []string{"shoot", "hit", "shoot", "shoot"} {
+		if !m.Play(name) {
+			t.Fatalf("%s did not play", name)
+		}
+	}
+	// the first shot is the oldest, and the hit is another sound
+	if got := ids(m); len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 4 {
+		t.Errorf("the voices are %v, want [2 3 4]", got)
+	}
+	if !strings.Contains(out.String(), "0 stop shoot#1 sfx") {
+		t.Errorf("the oldest shot was not stopped:\n%s", out.String())
+	}
+	if m.Play("laser") {
+		t.Error("a sound that was not added played")
+	}
+}
+
+func TestMaxVoicesOverall(t *testing.T) {
+	m := newMixer(nil, 60, &Sound{Name: "hit", Samples: samples(1), Category: SFX, Volume: 1})
+	for range MAX_VOICES + 8 {
+		m.Play("hit")
+	}
+	got := ids(m)
+	if len(got) != MAX_VOICES || got[0] != 9 || got[len(got)-1] != MAX_VOICES+8 {
+		t.Errorf("%d voices from #%d to #%d are playing, want %d from #9", len(got), got[0], got[len(got)-1], MAX_VOICES)
+	}
+}
+
+// TestEnd checks that voices play for as long as their samples, whatever
+// the tick rate is, and even when it changes while they play
+func TestEnd(t *testing.T) {
+	for _, tt := range []struct {
+		tps     []int // the tick rate for each half second
+		updates int
+	}{
+		{[]int{60}, 30},
+		{[]int{120}, 60},
+		{[]int{30, 120}, 15},
+		{[]int{120, 30}, 60},
+	} {
+		m := newMixer(nil, tt.tps[0], &Sound{Name: "hit", Samples: samples(0.5), Category: SFX, Volume: 1})
+		m.Play("hit")
+		updates := 0
+		for len(m.Voices()) > 0 && updates < 1000 {
+			m.TPS = tt.tps[min(int(m.Time*2), len(tt.tps)-1)]
+			m.Update()
+			updates++
+		}
+		if updates < tt.updates-1 || updates > tt.updates+1 {
+			t.Errorf("at %v ticks per second, the voice stopped after %d updates, want %d", tt.tps, updates, tt.updates)
+		}
+	}
+}
+
+func TestDuck(t *testing.T) {
+	for _, tps := range []int{60, 120} {
+		music := &Sound{Name: "music", Samples: samples(1), Category: Music, Volume: 1, Loop: true}
+		bomb := &Sound{Name: "bomb", Samples: samples(0.25), Category: SFX, Volume: 1, Duck: 0.6}
+		m := newMixer(nil, tps, music, bomb)
+		m.Play("music")
+		m.Play("bomb")
+		v := m.Voices()[0]
+		m.Update()
+		if math.Abs(v.Volume-0.4) > 1e-9 {
+			t.Errorf("at %d ticks per second, the music is at %g while the bomb plays, want 0.4", tps, v.Volume)
+		}
+		// the bomb plays for a quarter of a second, and then the music
+		// comes back up over half a second
+		seconds := 0.0
+		for v.Volume < 1 && seconds < 10 {
+			m.Update()
+			seconds += 1 / float64(tps)
+		}
+		if want := 0.25 + 0.6/DUCK_RELEASE; math.Abs(seconds-want) > 2/float64(tps) {
+			t.Errorf("at %d ticks per second, the music came back after %.3f seconds, want %.3f", tps, seconds, want)
+		}
+	}
+}
+
+func TestVolume(t *testing.T) {
+	var out bytes.Buffer
+	m := newMixer(&out, 60,
+		&Sound{Name: "music", Samples: samples(1), Category: Music, Volume: 0.5, Loop: true},
+		&Sound{Name: "menu", Samples: samples(1), Category: UI, Volume: 0.8},
+	)
+	m.Master = 0.5
+	m.Volumes[UI] = 0.5
+	m.Play("music")
+	m.Play("menu")
+	if music, menu := m.Voices()[0], m.Voices()[1]; music.Volume != 0.25 || menu.Volume != 0.2 {
+		t.Errorf("the music is at %g and the menu at %g, want 0.25 and 0.2", music.Volume, menu.Volume)
+	}
+	m.Volumes[Music] = 0
+	m.Update()
+	if v := m.Voices()[0]; v.Volume != 0 {
+		t.Errorf("the music is at %g with its category at 0", v.Volume)
+	}
+	if !strings.Contains(out.String(), "1 volume music#1 music volume=0.00 pitch=1.00\n") {
+		t.Errorf("the change of volume was not logged:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "volume menu") {
+		t.Errorf("the menu sound was changed without its volume changing:\n%s", out.String())
+	}
+}
+
+func TestJitter(t *testing.T) {
+	pitches := func() []float64 {
+		m := newMixer(nil, 60, &Sound{Name: "shoot", Samples: samples(1), Category: SFX, Volume: 1, Jitter: 0.1})
+		var pitches []float64
+		for range 20 {
+			m.Play("shoot")
+			pitches = append(pitches, m.Voices()[len(m.Voices())-1].Pitch)
+		}
+		return pitches
+	}
+	a, b := pitches(), pitches()
+	same := true
+	for i, p := range a {
+		if p < 0.9 || p > 1.1 {
+			t.Errorf("shot %d has a pitch of %g", i, p)
+		}
+		same = same && p == a[0]
+		if p != b[i] {
+			t.Errorf("shot %d has a pitch of %g and then %g from the same seed", i, p, b[i])
+		}
+	}
+	if same {
+		t.Error("every shot has the same pitch")
+	}
+}
@@ -0,0 +1,42 @@
+package mixer
+
+import (
+	"fmt"
+	"io"
+)
+
+// Null is a backend that plays nothing. It writes what it was told to do
+// to Out instead, if it is set, for checking the mix without sound.
+type Null struct {
+	Mixer *Mixer // for the tick in the log
+	Out   io.Writer
+	Err   error // the first error from writing to Out
+}
+
+// NewNull returns a mixer with a null backend that logs to out, which may
+// be nil, and is updated tps times per second
+func NewNull(out io.Writer, tps int) *Mixer {
+	n := &Null{Out: out}
+	n.Mixer = New(n, tps)
+	return n.Mixer
+}
+
+func (n *Null) Start(v *Voice) {
+	n.log("start", v)
+}
+
+func (n *Null) SetVolume(v *Voice) {
+	n.log("volume", v)
+}
+
+func (n *Null) Stop(v *Voice) {
+	n.log("stop", v)
+}
+
+// log writes a line like "120 start shoot#3 sfx volume=0.25 pitch=1.02"
+func (n *Null) log(what string, v *Voice) {
+	if n.Out == nil || n.Err != nil {
+		return
+	}
+	_, n.Err = fmt.Fprintf(n.Out, "%d %s %s#%d %s volume=%.2f pitch=%.2f\n", n.Mixer.Tick, what, v.Sound.Name, v.ID, v.Sound.Category, v.Volume, v.Pitch)
+}
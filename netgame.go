@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"hi/netplay"
+	"hi/sim"
+)
+
+var (
+	hostFlag    = flag.String("host", "", "host an online game, listening on this address, like :7777")
+	joinFlag    = flag.String("join", "", "join an online game at this address, like 192.168.1.2:7777")
+	delayFlag   = flag.Int("delay", 2, "frames of input delay for online games")
+	latencyFlag = flag.Duration("latency", 0, "add latency to outgoing packets, for testing online games")
+	jitterFlag  = flag.Duration("jitter", 0, "add up to this much random latency to outgoing packets")
+	lossFlag    = flag.Float64("loss", 0, "drop this fraction of outgoing packets, for testing online games")
+)
+
+// session is the online game, if there is one
+var session *netplay.Session
+
+// startNetplay hosts or joins an online game, if asked to on the command line
+func startNetplay() error {
+	var (
+		conn  netplay.Conn
+		local int
+		err   error
+	)
+	switch {
+	case *hostFlag != "":
+		fmt.Printf("Waiting for another player on %s\n", *hostFlag)
+		conn, err = netplay.Listen(*hostFlag, netplay.Game{Seed: world.Seed, Difficulty: world.Difficulty})
+	case *joinFlag != "":
+		var game netplay.Game
+		// the host decides the seed and difficulty for both
+		conn, game, err = netplay.Dial(*joinFlag)
+		world.SetSeed(game.Seed)
+		world.SetDifficulty(game.Difficulty)
+		local = 1
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if *latencyFlag > 0 || *jitterFlag > 0 || *lossFlag > 0 {
+		// from the seed of the game, and different for both players
+		conn = netplay.NewShim(conn, *latencyFlag, *jitterFlag, *lossFlag, int64(world.Seed)+int64(local))
+	}
+	session = netplay.NewSession(world, conn, local, *delayFlag)
+	return nil
+}
+
+// stepNetplay advances the online game with the input of the local player,
+// and returns false while it waits for the other player
+func stepNetplay() (bool, error) {
+	var in sim.Input
+	// the game goes on while the options menu or the console is open, without the keys that are used there
+	if !options.Open && !console.Open {
+		in = keyboard1.Input()
+	}
+	stepped, err := session.Update(in)
+	world = session.World
+	return stepped, err
+}
+
+// netStats is shown on the debug overlay during online games
+func netStats() string {
+	if session == nil {
+		return ""
+	}
+	return fmt.Sprintf("P%d DELAY %d FRAME %d\nROLLBACKS %d RESIM %d STALLS %d", session.Local+1, session.Delay, session.Frame(), session.Rollbacks, session.Resimed, session.Stalls)
+}
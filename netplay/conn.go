@@ -0,0 +1,204 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Conn sends and receives packets to and from the other player
+type Conn interface {
+	Send(packet []byte) error
+	// Recv returns the next packet that has arrived, without waiting for one
+	Recv() ([]byte, bool)
+	Close() error
+}
+
+// HANDSHAKE_TIMEOUT is how long Dial keeps trying to reach the host
+const HANDSHAKE_TIMEOUT = 10 * time.Second
+
+// UDPConn is a Conn to a single peer. Packets are read on a goroutine, so
+// that Recv never blocks the game loop.
+type UDPConn struct {
+	conn    *net.UDPConn
+	peer    *net.UDPAddr
+	packets chan []byte
+	welcome []byte // sent again if the hello is repeated, in case the welcome was lost
+}
+
+func newUDPConn(conn *net.UDPConn, peer *net.UDPAddr) *UDPConn {
+	c := &UDPConn{conn: conn, peer: peer, packets: make(chan []byte, 256)}
+	go c.read()
+	return c
+}
+
+func (c *UDPConn) read() {
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			close(c.packets)
+			return
+		}
+		if !from.IP.Equal(c.peer.IP) || from.Port != c.peer.Port {
+			continue
+		}
+		if t, _, err := decode(buf[:n]); err == nil && t == HELLO {
+			if c.welcome != nil {
+				c.conn.WriteToUDP(c.welcome, c.peer)
+			}
+			continue
+		}
+		select {
+		case c.packets <- append([]byte(nil), buf[:n]...):
+		default:
+			// the game is not keeping up, drop the packet like the network would
+		}
+	}
+}
+
+func (c *UDPConn) Send(packet []byte) error {
+	_, err := c.conn.WriteToUDP(packet, c.peer)
+	return err
+}
+
+func (c *UDPConn) Recv() ([]byte, bool) {
+	select {
+	case p, ok := <-c.packets:
+		return p, ok
+	default:
+		return nil, false
+	}
+}
+
+func (c *UDPConn) Close() error {
+	return c.conn.Close()
+}
+
+// Listen waits on addr until another player says hello, and welcomes them
+// with the seed for the random number generator and the difficulty
+func Listen(addr string, game Game) (*UDPConn, error) {
+	laddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if t, _, err := decode(buf[:n]); err == nil && t == HELLO {
+			c := &UDPConn{conn: conn, peer: from, packets: make(chan []byte, 256), welcome: welcome(game)}
+			go c.read()
+			return c, c.Send(c.welcome)
+		}
+	}
+}
+
+// Dial says hello to the host at addr until it is welcomed, and returns
+// the seed and difficulty that the host picked
+func Dial(addr string) (*UDPConn, Game, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, Game{}, err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, Game{}, err
+	}
+	buf := make([]byte, 2048)
+	deadline := time.Now().Add(HANDSHAKE_TIMEOUT)
+	for time.Now().Before(deadline) {
+		if _, err := conn.WriteToUDP(hello(), raddr); err != nil {
+			conn.Close()
+			return nil, Game{}, err
+		}
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+		if !from.IP.Equal(raddr.IP) || from.Port != raddr.Port {
+			continue
+		}
+		t, body, err := decode(buf[:n])
+		if err != nil || t != WELCOME {
+			continue
+		}
+		game, err := decodeWelcome(body)
+		if err != nil {
+			conn.Close()
+			return nil, Game{}, fmt.Errorf("%s: %w", addr, err)
+		}
+		conn.SetReadDeadline(time.Time{})
+		return newUDPConn(conn, raddr), game, nil
+	}
+	conn.Close()
+	return nil, Game{}, fmt.Errorf("no answer from %s", addr)
+}
+
+// Shim makes a Conn behave like a bad network, by delaying and dropping
+// the packets that are sent. It is for trying out the rollback on localhost.
+type Shim struct {
+	Conn
+	Latency time.Duration // added to every packet
+	Jitter  time.Duration // random extra latency, up to this much
+	Loss    float64       // the fraction of packets that are dropped
+	rand    *rand.Rand
+	queue   []delayed
+}
+
+type delayed struct {
+	at     time.Time
+	packet []byte
+}
+
+// NewShim wraps c. The same seed drops the same packets and picks the same
+// jitter, so that a run can be repeated.
+func NewShim(c Conn, latency, jitter time.Duration, loss float64, seed int64) *Shim {
+	return &Shim{Conn: c, Latency: latency, Jitter: jitter, Loss: loss, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (s *Shim) Send(packet []byte) error {
+	if s.rand.Float64() < s.Loss {
+		return nil
+	}
+	at := time.Now().Add(s.Latency)
+	if s.Jitter > 0 {
+		at = at.Add(time.Duration(s.rand.Int63n(int64(s.Jitter))))
+	}
+	s.queue = append(s.queue, delayed{at, append([]byte(nil), packet...)})
+	return s.flush()
+}
+
+func (s *Shim) Recv() ([]byte, bool) {
+	if err := s.flush(); err != nil {
+		return nil, false
+	}
+	return s.Conn.Recv()
+}
+
+// flush sends the packets that have been delayed long enough. Jitter may
+// reorder them, like a real network can.
+func (s *Shim) flush() error {
+	now := time.Now()
+	queue := s.queue[:0]
+	var errs []error
+	for _, d := range s.queue {
+		if now.Before(d.at) {
+			queue = append(queue, d)
+			continue
+		}
+		errs = append(errs, s.Conn.Send(d.packet))
+	}
+	s.queue = queue
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package netplay
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"hi/sim"
+)
+
+// Packet types. Every packet starts with "hi", the protocol version and the type.
+const (
+	HELLO   = iota + 1 // the joining player asks to play
+	WELCOME            // the host answers with the seed and the difficulty
+	INPUT              // inputs for a range of frames, and how many of the other inputs have arrived
+	QUIT               // the other player has left
+)
+
+const VERSION = 2
+
+// MAX_INPUTS is the largest number of inputs sent in one packet
+const MAX_INPUTS = 64
+
+var order = binary.BigEndian
+
+var errPacket = errors.New("not a packet for this game")
+
+func header(t byte) []byte {
+	return []byte{'h', 'i', VERSION, t}
+}
+
+func hello() []byte {
+	return header(HELLO)
+}
+
+// Game is what the host picks for both players
+type Game struct {
+	Seed       uint64
+	Difficulty sim.Difficulty
+}
+
+// welcome holds the seed, whether the difficulty is adaptive, and the name
+// of the difficulty preset
+func welcome(g Game) []byte {
+	p := order.AppendUint64(header(WELCOME), g.Seed)
+	adaptive := byte(0)
+	if g.Difficulty.Adaptive {
+		adaptive = 1
+	}
+	p = append(p, adaptive)
+	return append(p, g.Difficulty.Name...)
+}
+
+func decodeWelcome(body []byte) (Game, error) {
+	if len(body) < 9 || body[8] > 1 {
+		return Game{}, errPacket
+	}
+	d, err := sim.FindDifficulty(string(body[9:]))
+	if err != nil {
+		return Game{}, err
+	}
+	d.Adaptive = body[8] == 1
+	return Game{order.Uint64(body), d}, nil
+}
+
+func quit() []byte {
+	return header(QUIT)
+}
+
+// inputPacket holds the inputs for frames start, start+1 and so on, and ack,
+// which is the number of inputs that the sender has from the receiver
+func inputPacket(ack, start uint32, inputs []sim.Input) []byte {
+	p := order.AppendUint32(header(INPUT), ack)
+	p = order.AppendUint32(p, start)
+	p = append(p, byte(len(inputs)))
+	for _, in := range inputs {
+		p = order.AppendUint16(p, uint16(in))
+	}
+	return p
+}
+
+// decode returns the type and the body of the packet
+func decode(p []byte) (byte, []byte, error) {
+	if len(p) < 4 || p[0] != 'h' || p[1] != 'i' || p[2] != VERSION {
+		return 0, nil, errPacket
+	}
+	return p[3], p[4:], nil
+}
+
+func decodeInputs(body []byte) (ack, start uint32, inputs []sim.Input, err error) {
+	if len(body) < 9 || len(body) != 9+2*int(body[8]) {
+		return 0, 0, nil, errPacket
+	}
+	ack, start = order.Uint32(body), order.Uint32(body[4:])
+	for i := 0; i < int(body[8]); i++ {
+		inputs = append(inputs, sim.Input(order.Uint16(body[9+2*i:])))
+	}
+	return ack, start, inputs, nil
+}
@@ -0,0 +1,205 @@
+// Package netplay is online co-op for two players with rollback netcode.
+//
+// Both players run the whole simulation. Local inputs are delayed by a few
+// frames and sent to the other player, along with the inputs that have not
+// been acknowledged yet, so that lost packets do not matter. When the input
+// of the other player has not arrived in time, it is predicted to be the
+// same as the last one that did arrive. When the real input turns out to
+// be different, the world is rolled back to the snapshot from before that
+// frame and simulated forward again with the right inputs.
+//
+// The events of a frame are only handed out by Events once the frame is
+// confirmed, when the inputs of both players for it are known, so that
+// sounds and statistics never see what a wrong prediction did. When the
+// inputs arrive in time, that is as soon as the frame is simulated.
+//
+// The host listens on a UDP port, and the joining player says hello until
+// the host answers with a welcome. After that, both send inputs every frame.
+package netplay
+
+import (
+	"errors"
+	"time"
+
+	"hi/sim"
+)
+
+const (
+	// RING is the number of frames of inputs and snapshots that are kept
+	RING = 128
+
+	// MAX_PREDICTION is how many frames the simulation may run ahead of the
+	// last input that arrived from the other player, before it waits
+	MAX_PREDICTION = 8
+
+	// TIMEOUT is how long to wait for the other player before giving up
+	TIMEOUT = 5 * time.Second
+)
+
+// ErrQuit is returned by Update when the other player has left the game
+var ErrQuit = errors.New("the other player left the game")
+
+// ErrTimeout is returned by Update when nothing has been heard from the other player for a while
+var ErrTimeout = errors.New("lost the connection to the other player")
+
+// Session runs a world for two players on different machines
+type Session struct {
+	World *sim.World
+	Local int // the player that is controlled on this machine, 0 for the host
+	Delay int // frames of input delay
+
+	Rollbacks int // number of times the world has been rolled back
+	Resimed   int // number of frames that have been simulated again
+	Stalls    int // number of ticks spent waiting for the other player
+
+	conn  Conn
+	frame uint64 // the next frame to simulate
+
+	local      [RING]sim.Input
+	localCount uint64 // number of local inputs, including the delay
+	acked      uint64 // number of local inputs that the other player has
+
+	remote      [RING]sim.Input
+	remoteCount uint64 // number of inputs from the other player, in order
+	predicted   [RING]sim.Input
+
+	snapshots [RING]*sim.World  // the world before each frame
+	events    [RING][]sim.Event // the events of each frame
+	confirmed uint64            // number of frames whose events have been handed out
+	heard     time.Time
+}
+
+// NewSession starts a session where both players are in the game. The local
+// player is 0 for the host and 1 for the player that joined.
+func NewSession(w *sim.World, conn Conn, local, delay int) *Session {
+	s := &Session{World: w, Local: local, Delay: delay, conn: conn, heard: time.Now()}
+	// the second player joins before the first frame, on both machines
+	w.Step([]sim.Input{0, sim.Join})
+	// the first frames have no input, because of the delay
+	s.localCount = uint64(delay)
+	return s
+}
+
+// Update reads the packets that have arrived, rolls back if a prediction was
+// wrong, and simulates the next frame with the given local input. When the
+// other player is too far behind, it waits and returns false.
+func (s *Session) Update(in sim.Input) (bool, error) {
+	if err := s.poll(); err != nil {
+		return false, err
+	}
+	if s.frame >= s.remoteCount+MAX_PREDICTION {
+		s.Stalls++
+		return false, s.send()
+	}
+	s.local[s.localCount%RING] = in
+	s.localCount++
+	if err := s.send(); err != nil {
+		return false, err
+	}
+	s.simulate(s.frame)
+	s.frame++
+	return true, nil
+}
+
+// Events returns the events of the frames that were confirmed since the
+// last call, in order. Every frame is handed out once, with the events it
+// has after any rollback. It is called after every Update.
+func (s *Session) Events() []sim.Event {
+	var events []sim.Event
+	for ; s.confirmed < min(s.frame, s.remoteCount); s.confirmed++ {
+		events = append(events, s.events[s.confirmed%RING]...)
+	}
+	return events
+}
+
+// Frame returns the number of frames that have been simulated
+func (s *Session) Frame() uint64 {
+	return s.frame
+}
+
+// Close tells the other player that this one is leaving
+func (s *Session) Close() error {
+	s.conn.Send(quit())
+	return s.conn.Close()
+}
+
+// poll handles the packets that have arrived, and rolls back to the
+// earliest frame where the prediction was wrong
+func (s *Session) poll() error {
+	rollback := s.frame
+	for {
+		p, ok := s.conn.Recv()
+		if !ok {
+			break
+		}
+		t, body, err := decode(p)
+		if err != nil {
+			continue
+		}
+		s.heard = time.Now()
+		switch t {
+		case QUIT:
+			return ErrQuit
+		case INPUT:
+			ack, start, inputs, err := decodeInputs(body)
+			if err != nil {
+				continue
+			}
+			s.acked = max(s.acked, uint64(ack))
+			for i, in := range inputs {
+				f := uint64(start) + uint64(i)
+				if f != s.remoteCount {
+					// already have it, or there is a gap that a later packet fills
+					continue
+				}
+				s.remote[f%RING] = in
+				s.remoteCount++
+				if f < s.frame && s.predicted[f%RING] != in {
+					rollback = min(rollback, f)
+				}
+			}
+		}
+	}
+	if time.Since(s.heard) > TIMEOUT {
+		return ErrTimeout
+	}
+	if rollback < s.frame {
+		s.Rollbacks++
+		s.World = s.snapshots[rollback%RING].Clone()
+		for f := rollback; f < s.frame; f++ {
+			s.simulate(f)
+			s.Resimed++
+		}
+	}
+	return nil
+}
+
+// send sends the local inputs that the other player does not have yet
+func (s *Session) send() error {
+	start := max(s.acked, s.localCount-min(s.localCount, RING))
+	end := min(s.localCount, start+MAX_INPUTS)
+	inputs := make([]sim.Input, 0, end-start)
+	for f := start; f < end; f++ {
+		inputs = append(inputs, s.local[f%RING])
+	}
+	return s.conn.Send(inputPacket(uint32(s.remoteCount), uint32(start), inputs))
+}
+
+// simulate saves a snapshot and steps the world through frame f, predicting
+// the input of the other player if it has not arrived yet
+func (s *Session) simulate(f uint64) {
+	s.snapshots[f%RING] = s.World.Clone()
+	var remote sim.Input
+	switch {
+	case f < s.remoteCount:
+		remote = s.remote[f%RING]
+	case s.remoteCount > 0:
+		remote = s.remote[(s.remoteCount-1)%RING]
+	}
+	s.predicted[f%RING] = remote
+	inputs := make([]sim.Input, 2)
+	inputs[s.Local] = s.local[f%RING]
+	inputs[1-s.Local] = remote
+	s.World.Step(inputs)
+	s.events[f%RING] = append(s.events[f%RING][:0], s.World.Events...)
+}
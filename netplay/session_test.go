@@ -0,0 +1,241 @@
+package netplay
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"hi/sim"
+)
+
+// newWorld returns the first level with a fixed seed, like both players
+// have after the welcome
+func newWorld(t *testing.T) *sim.World {
+	t.Helper()
+	if err := sim.LoadData(".."); err != nil {
+		t.Fatal(err)
+	}
+	w, err := sim.LoadLevel("../levels/1.lvl", 1234, sim.DefaultDifficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// input is what player p holds in the call to Update for frame f. It
+// changes every few frames, so that the predictions are often wrong.
+func input(p int, f uint64) sim.Input {
+	x := (uint64(p)*7919 + f/6) * 0x9e3779b97f4a7c15
+	x ^= x >> 29
+	return sim.Input(x) & (sim.Left | sim.Right | sim.Up | sim.Down | sim.Fire | sim.Focus)
+}
+
+// freePort returns an address on localhost that nothing listens on
+func freePort(t *testing.T) string {
+	t.Helper()
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	return c.LocalAddr().String()
+}
+
+// TestRollback plays two sessions against each other on localhost, through
+// a shim that delays, reorders and drops packets, and checks that both end
+// up with the same world and the same events as one that was stepped with
+// all the inputs
+func TestRollback(t *testing.T) {
+	const FRAMES, DELAY = 600, 2
+	addr := freePort(t)
+	hosted := make(chan Conn)
+	errs := make(chan error, 1)
+	go func() {
+		c, err := Listen(addr, Game{Seed: 1234, Difficulty: sim.DefaultDifficulty})
+		if err != nil {
+			errs <- err
+			return
+		}
+		hosted <- c
+	}()
+	joined, game, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var host Conn
+	select {
+	case host = <-hosted:
+	case err := <-errs:
+		t.Fatal(err)
+	}
+	if game.Seed != 1234 || game.Difficulty != sim.DefaultDifficulty {
+		t.Errorf("the welcome says %+v", game)
+	}
+
+	sessions := []*Session{
+		NewSession(newWorld(t), NewShim(host, 20*time.Millisecond, 10*time.Millisecond, 0.2, 1), 0, DELAY),
+		NewSession(newWorld(t), NewShim(joined, 20*time.Millisecond, 10*time.Millisecond, 0.2, 2), 1, DELAY),
+	}
+	defer func() {
+		for _, s := range sessions {
+			s.Close()
+		}
+	}()
+	// every session plays its frames, and then goes on sending and
+	// receiving until it has all the inputs of the other player, so that
+	// nothing in its world is predicted any more
+	done := func(s *Session) bool {
+		return s.frame >= FRAMES && s.remoteCount >= FRAMES
+	}
+	var events [2][]string
+	deadline := time.Now().Add(30 * time.Second)
+	for !done(sessions[0]) || !done(sessions[1]) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the sessions are at frames %d and %d after 30 seconds", sessions[0].frame, sessions[1].frame)
+		}
+		for i, s := range sessions {
+			var err error
+			if s.frame < FRAMES {
+				_, err = s.Update(input(i, s.frame))
+			} else if err = s.poll(); err == nil {
+				err = s.send()
+			}
+			if err != nil {
+				t.Fatalf("player %d: %v", i+1, err)
+			}
+			for _, e := range s.Events() {
+				events[i] = append(events[i], e.String())
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := newWorld(t)
+	want.Step([]sim.Input{0, sim.Join})
+	var wantEvents []string
+	for f := uint64(0); f < FRAMES; f++ {
+		var inputs [2]sim.Input
+		if f >= DELAY {
+			inputs = [2]sim.Input{input(0, f-DELAY), input(1, f-DELAY)}
+		}
+		want.Step(inputs[:])
+		for _, e := range want.Events {
+			wantEvents = append(wantEvents, e.String())
+		}
+	}
+	for i, s := range sessions {
+		if got := s.World.Hash(); got != want.Hash() {
+			t.Errorf("player %d: the hash is %x at tick %d, want %x at tick %d", i+1, got, s.World.Tick, want.Hash(), want.Tick)
+		}
+		if !slices.Equal(events[i], wantEvents) {
+			t.Errorf("player %d: got %d events, want %d, or they differ", i+1, len(events[i]), len(wantEvents))
+		}
+	}
+	if sessions[0].Rollbacks+sessions[1].Rollbacks == 0 {
+		t.Error("no prediction was wrong, so nothing was rolled back")
+	}
+}
+
+// conn records the packets that are sent through it, and receives the
+// ones that are queued in recv
+type conn struct {
+	sent [][]byte
+	recv [][]byte
+}
+
+func (c *conn) Send(packet []byte) error {
+	c.sent = append(c.sent, packet)
+	return nil
+}
+
+func (c *conn) Recv() ([]byte, bool) {
+	if len(c.recv) == 0 {
+		return nil, false
+	}
+	p := c.recv[0]
+	c.recv = c.recv[1:]
+	return p, true
+}
+
+func (c *conn) Close() error { return nil }
+
+// TestShimSeed checks that the same seed drops the same packets
+func TestShimSeed(t *testing.T) {
+	sent := func(seed int64) []byte {
+		c := &conn{}
+		s := NewShim(c, 0, 0, 0.5, seed)
+		for i := range 100 {
+			s.Send([]byte{byte(i)})
+		}
+		var got []byte
+		for _, p := range c.sent {
+			got = append(got, p[0])
+		}
+		return got
+	}
+	a, b := sent(7), sent(7)
+	if string(a) != string(b) {
+		t.Errorf("the same seed sent %v and %v", a, b)
+	}
+	if len(a) == 0 || len(a) == 100 {
+		t.Errorf("%d of 100 packets were sent with a loss of 0.5", len(a))
+	}
+}
+
+// TestStall checks that a session that waits for the other player does not
+// hand out any events again, and hands out every frame once the inputs arrive
+func TestStall(t *testing.T) {
+	c := &conn{}
+	s := NewSession(newWorld(t), c, 0, 0)
+	want := newWorld(t)
+	want.Step([]sim.Input{0, sim.Join})
+	var wantEvents []string
+	for range MAX_PREDICTION {
+		want.Step([]sim.Input{sim.Fire, 0})
+		for _, e := range want.Events {
+			wantEvents = append(wantEvents, e.String())
+		}
+	}
+
+	var events []string
+	update := func() bool {
+		t.Helper()
+		stepped, err := s.Update(sim.Fire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range s.Events() {
+			events = append(events, e.String())
+		}
+		return stepped
+	}
+	for f := range MAX_PREDICTION {
+		if !update() {
+			t.Fatalf("the session waited at frame %d", f)
+		}
+	}
+	// nothing has come from the other player, so it waits, and nothing is confirmed
+	for range 10 {
+		if update() {
+			t.Fatal("the session went on without the other player")
+		}
+	}
+	if len(events) != 0 {
+		t.Fatalf("got the events %q of frames that are not confirmed", events)
+	}
+	// the other player held nothing, as predicted
+	c.recv = append(c.recv, inputPacket(0, 0, make([]sim.Input, MAX_PREDICTION)))
+	if !update() {
+		t.Fatal("the session still waits after the inputs arrived")
+	}
+	for range 10 {
+		update()
+	}
+	if !slices.Equal(events, wantEvents) {
+		t.Errorf("got the events\n%q\nwant\n%q", events, wantEvents)
+	}
+	if s.Stalls == 0 || s.Rollbacks != 0 {
+		t.Errorf("%d stalls and %d rollbacks", s.Stalls, s.Rollbacks)
+	}
+}
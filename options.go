@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Option is a line in the options menu. Left and right change the value,
+// and enter activates it.
+type Option struct {
+	Name     string // the message ID of the name
+	Args     []any  // for the name
+	Value    func() string
+	Change   func(delta int)
+	Activate func()
+}
+
+// Options is the options menu, which pauses the game while it is open,
+// unless the game is online
+type Options struct {
+	Open      bool
+	Items     []Option
+	selected  int
+	scroll    int
+	rebinding *ebiten.Key // the key that is waiting for a new key to be pressed
+	stats     bool        // the statistics are shown instead of the menu
+	quit      bool
+}
+
+var (
+	menuColor     = color.NRGBA{0x00, 0x00, 0x24, 0xdb}
+	selectedColor = color.RGBA{0x24, 0x24, 0x92, 0xff}
+)
+
+// OPTION_LINES is the number of options that fit on the screen at once
+const OPTION_LINES = 16
+
+var options *Options
+
+func newOptions() *Options {
+	o := &Options{}
+	o.Items = []Option{
+		{Name: "menu.resume", Activate: func() { o.Close() }},
+		{Name: "menu.scale", Value: func() string { return fmt.Sprintf("%dx", settings.Video.Scale) }, Change: func(d int) {
+			settings.Video.Scale = min(max(settings.Video.Scale+d, 1), MAX_SCALE)
+		}},
+		toggle("menu.fullscreen", &settings.Video.Fullscreen),
+		toggle("menu.vsync", &settings.Video.VSync),
+	}
+	if postFX != nil {
+		for _, e := range postFX.Effects {
+			name := e.Name
+			o.Items = append(o.Items, Option{Name: "menu.effect", Args: []any{name}, Value: func() string { return onOff(settings.Video.Effects[name]) }, Change: func(int) {
+				settings.Video.Effects[name] = !settings.Video.Effects[name]
+			}})
+		}
+	}
+	o.Items = append(o.Items,
+		volume("menu.master-volume", &settings.Audio.Master),
+		volume("menu.music-volume", &settings.Audio.Music),
+		volume("menu.effects-volume", &settings.Audio.Effects),
+		volume("menu.menu-volume", &settings.Audio.UI),
+		Option{Name: "menu.difficulty", Value: func() string { return msg("difficulty." + settings.Gameplay.Difficulty) }, Change: func(d int) {
+			i := slices.Index(difficulties, settings.Gameplay.Difficulty) + d
+			settings.Gameplay.Difficulty = difficulties[min(max(i, 0), len(difficulties)-1)]
+		}},
+		toggle("menu.adaptive", &settings.Gameplay.Adaptive),
+		volume("menu.shake", &settings.Gameplay.Shake),
+		Option{Name: "menu.language", Value: func() string { return lang.Name }, Change: func(d int) {
+			codes := languageCodes()
+			i := slices.Index(codes, lang.Code) + d
+			settings.Language = codes[min(max(i, 0), len(codes)-1)]
+		}},
+		Option{Name: "menu.palette", Value: func() string { return msg("palette." + settings.Access.Palette) }, Change: func(d int) {
+			names := paletteNames()
+			i := slices.Index(names, settings.Access.Palette) + d
+			settings.Access.Palette = names[min(max(i, 0), len(names)-1)]
+		}},
+		toggle("menu.high-contrast", &settings.Access.HighContrast),
+		toggle("menu.reduced-motion", &settings.Access.ReducedMotion),
+		toggle("menu.hold-to-fire", &settings.Access.HoldToFire),
+		Option{Name: "menu.speed", Value: func() string { return fmt.Sprintf("%d%%", int(settings.Access.Speed*100+0.5)) }, Change: func(d int) {
+			// in tenths, like the volumes
+			tenths := min(max(int(settings.Access.Speed*10+0.5)+d, MIN_SPEED*10), 10)
+			settings.Access.Speed = float64(tenths) / 10
+		}},
+	)
+	for i, k := range []*Keyboard{&settings.Controls.Keyboard1, &settings.Controls.Keyboard2} {
+		for _, b := range []struct {
+			name string
+			key  *ebiten.Key
+		}{
+			{"left", &k.Left}, {"right", &k.Right}, {"up", &k.Up}, {"down", &k.Down},
+			{"fire", &k.Fire}, {"missile", &k.Missile}, {"focus", &k.Focus}, {"bomb", &k.Bomb},
+			{"join", &k.Join}, {"leave", &k.Leave},
+		} {
+			key := b.key
+			o.Items = append(o.Items, Option{
+				Name: "menu.keys." + b.name,
+				Args: []any{i + 1},
+				Value: func() string {
+					if o.rebinding == key {
+						return msg("menu.press-key")
+					}
+					return key.String()
+				},
+				Activate: func() { o.rebinding = key },
+			})
+		}
+	}
+	o.Items = append(o.Items,
+		Option{Name: "menu.reset-controls", Activate: func() {
+			settings.Controls.Keyboard1 = defaultKeyboards[0]
+			settings.Controls.Keyboard2 = defaultKeyboards[1]
+		}},
+		Option{Name: "menu.statistics", Activate: func() { o.stats = true }},
+		Option{Name: "menu.quit", Activate: func() { o.quit = true }},
+	)
+	return o
+}
+
+func toggle(name string, b *bool) Option {
+	return Option{Name: name, Value: func() string { return onOff(*b) }, Change: func(int) { *b = !*b }}
+}
+
+// volume is an option for a value from 0 to 1, in steps of a tenth
+func volume(name string, v *float64) Option {
+	return Option{Name: name, Value: func() string { return fmt.Sprintf("%d", int(*v*10+0.5)) }, Change: func(d int) {
+		*v = float64(min(max(int(*v*10+0.5)+d, 0), 10)) / 10
+	}}
+}
+
+func onOff(b bool) string {
+	if b {
+		return msg("menu.on")
+	}
+	return msg("menu.off")
+}
+
+// Close closes the menu and saves the settings
+func (o *Options) Close() {
+	o.Open = false
+	o.rebinding = nil
+	o.stats = false
+	saveSettings()
+}
+
+// Update handles the menu keys, and returns false when the game should quit
+func (o *Options) Update() bool {
+	if o.stats {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			o.stats = false
+		}
+		return true
+	}
+	if o.rebinding != nil {
+		keys := inpututil.AppendJustPressedKeys(nil)
+		if len(keys) > 0 {
+			if keys[0] != ebiten.KeyEscape {
+				*o.rebinding = keys[0]
+				settings.Apply()
+			}
+			o.rebinding = nil
+		}
+		return true
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		o.Close()
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowUp):
+		o.selected = (o.selected + len(o.Items) - 1) % len(o.Items)
+		mix.Play("menu")
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowDown):
+		o.selected = (o.selected + 1) % len(o.Items)
+		mix.Play("menu")
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft):
+		o.change(-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowRight):
+		o.change(1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		if item := o.Items[o.selected]; item.Activate != nil {
+			item.Activate()
+			settings.Apply()
+			mix.Play("menu")
+		} else {
+			o.change(1)
+		}
+	}
+	o.scroll = min(max(o.scroll, o.selected-OPTION_LINES+1), o.selected)
+	return !o.quit
+}
+
+func (o *Options) change(delta int) {
+	if item := o.Items[o.selected]; item.Change != nil {
+		item.Change(delta)
+		settings.Apply()
+		mix.Play("menu")
+	}
+}
+
+// Draw draws the menu on top of the game
+func (o *Options) Draw(screen *ebiten.Image) {
+	if o.stats {
+		drawStats(screen)
+		return
+	}
+	const x, y, lineH, width = 40, 16, 12, W - 80
+	vector.FillRect(screen, x-4, y-4, width+8, OPTION_LINES*lineH+8, menuColor, false)
+	for i := o.scroll; i < len(o.Items) && i < o.scroll+OPTION_LINES; i++ {
+		item := o.Items[i]
+		ly := y + (i-o.scroll)*lineH
+		if i == o.selected {
+			vector.FillRect(screen, x-2, float32(ly), width+4, lineH, selectedColor, false)
+		}
+		ebitenutil.DebugPrintAt(screen, msg(item.Name, item.Args...), x, ly-2)
+		if item.Value != nil {
+			value := item.Value()
+			ebitenutil.DebugPrintAt(screen, value, x+width-textWidth(value), ly-2)
+		}
+	}
+}
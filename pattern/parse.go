@@ -0,0 +1,243 @@
+package pattern
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hi/textfile"
+)
+
+// parser reads actions from pattern files into a Set. Bullet actions may be
+// used before they are defined, and even in another file, so they are
+// resolved after all files have been read.
+type parser struct {
+	set      Set
+	filename string
+}
+
+// LoadDir loads all .pat files in dir
+func LoadDir(dir string) (Set, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.pat"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+	p := &parser{set: make(Set)}
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		err = p.parse(f, filename)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.set, p.resolve()
+}
+
+// Parse reads the actions in r. The filename is only used for error messages.
+func Parse(r io.Reader, filename string) (Set, error) {
+	p := &parser{set: make(Set)}
+	if err := p.parse(r, filename); err != nil {
+		return nil, err
+	}
+	return p.set, p.resolve()
+}
+
+func (p *parser) errorf(line int, format string, args ...any) error {
+	return textfile.Errorf(p.filename, line, format, args...)
+}
+
+// frame is an action or repeat block that is being parsed
+type frame struct {
+	line     int
+	commands *[]Command
+}
+
+func (p *parser) parse(r io.Reader, filename string) error {
+	p.filename = filename
+	scanner := bufio.NewScanner(r)
+	var stack []frame
+	var action *Action
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if action == nil {
+			if fields[0] != "action" || len(fields) != 2 {
+				return p.errorf(line, "expected: action <name>")
+			}
+			if p.set[fields[1]] != nil {
+				return p.errorf(line, "action %q is already defined", fields[1])
+			}
+			action = &Action{Name: fields[1], Filename: filename}
+			p.set[action.Name] = action
+			stack = []frame{{line, &action.Commands}}
+			continue
+		}
+		if fields[0] == "end" {
+			if len(fields) != 1 {
+				return p.errorf(line, "unexpected %q after end", fields[1])
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				action = nil
+			}
+			continue
+		}
+		cmd, err := p.command(line, fields)
+		if err != nil {
+			return err
+		}
+		top := stack[len(stack)-1].commands
+		*top = append(*top, cmd)
+		if cmd.Op == Repeat {
+			last := &(*top)[len(*top)-1]
+			stack = append(stack, frame{line, &last.Body})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(stack) > 0 {
+		return p.errorf(stack[len(stack)-1].line, "missing end")
+	}
+	return nil
+}
+
+// command parses a single line that is not "action" or "end"
+func (p *parser) command(line int, fields []string) (Command, error) {
+	cmd := Command{Line: line}
+	args := fields[1:]
+	var err error
+	switch fields[0] {
+	case "fire":
+		cmd.Op = Fire
+		cmd.Dir = Value{Aim, 0}
+		cmd.Speed = Value{Absolute, 1}
+		for _, arg := range args {
+			key, value, _ := strings.Cut(arg, "=")
+			switch key {
+			case "dir":
+				cmd.Dir, err = parseValue(value, true)
+			case "speed":
+				cmd.Speed, err = parseValue(value, false)
+			case "bullet":
+				// resolved after all files have been parsed
+				cmd.bullet = value
+			default:
+				err = fmt.Errorf("unknown option %q", arg)
+			}
+			if err != nil {
+				return cmd, p.errorf(line, "%v", err)
+			}
+		}
+	case "repeat", "wait":
+		cmd.Op = Repeat
+		if fields[0] == "wait" {
+			cmd.Op = Wait
+		}
+		if len(args) != 1 {
+			return cmd, p.errorf(line, "usage: %s <count>", fields[0])
+		}
+		if cmd.Count, err = strconv.Atoi(args[0]); err != nil || cmd.Count < 1 {
+			return cmd, p.errorf(line, "invalid count %q", args[0])
+		}
+	case "direction", "speed":
+		if len(args) != 2 {
+			return cmd, p.errorf(line, "usage: %s <value> <ticks>", fields[0])
+		}
+		if fields[0] == "direction" {
+			cmd.Op = Direction
+			cmd.Dir, err = parseValue(args[0], true)
+		} else {
+			cmd.Op = Speed
+			cmd.Speed, err = parseValue(args[0], false)
+		}
+		if err != nil {
+			return cmd, p.errorf(line, "%v", err)
+		}
+		if cmd.Count, err = strconv.Atoi(args[1]); err != nil || cmd.Count < 1 {
+			return cmd, p.errorf(line, "invalid number of ticks %q", args[1])
+		}
+	case "vanish":
+		cmd.Op = Vanish
+		if len(args) != 0 {
+			return cmd, p.errorf(line, "vanish takes no arguments")
+		}
+	default:
+		return cmd, p.errorf(line, "unknown command %q", fields[0])
+	}
+	return cmd, nil
+}
+
+// resolve looks up the bullet actions that fire commands refer to
+func (p *parser) resolve() error {
+	names := make([]string, 0, len(p.set))
+	for name := range p.set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := p.resolveCommands(p.set[name], p.set[name].Commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) resolveCommands(action *Action, commands []Command) error {
+	for i := range commands {
+		cmd := &commands[i]
+		if cmd.bullet != "" {
+			if cmd.Bullet = p.set[cmd.bullet]; cmd.Bullet == nil {
+				return textfile.Errorf(action.Filename, cmd.Line, "unknown bullet action %q", cmd.bullet)
+			}
+		}
+		if err := p.resolveCommands(action, cmd.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseValue parses a direction in degrees, or a speed
+func parseValue(s string, direction bool) (Value, error) {
+	v := Value{Mode: Absolute}
+	num := s
+	switch {
+	case direction && s == "aim":
+		return Value{Aim, 0}, nil
+	case direction && strings.HasPrefix(s, "aim"):
+		v.Mode, num = Aim, s[len("aim"):]
+	case strings.HasPrefix(s, "seq"):
+		v.Mode, num = Sequence, s[len("seq"):]
+	case strings.HasPrefix(s, "rel"):
+		v.Mode, num = Relative, s[len("rel"):]
+	}
+	if v.Mode != Absolute && !strings.HasPrefix(num, "+") && !strings.HasPrefix(num, "-") {
+		return v, fmt.Errorf("invalid value %q, expected a sign after %q", s, s[:len(s)-len(num)])
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return v, fmt.Errorf("invalid value %q", s)
+	}
+	if direction {
+		f = f * math.Pi / 180
+	}
+	v.Value = f
+	return v, nil
+}
@@ -0,0 +1,110 @@
+package pattern
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+func TestParse(t *testing.T) {
+	src := `# a ring, then a curling bullet
+action ring
+  repeat 12
+    fire dir=seq+30 speed=0.8
+  end
+  wait 30
+  fire bullet=curl # aimed, at the default speed
+end
+
+action curl
+  direction rel-90 20
+  speed seq+0.5 10
+  vanish
+end
+`
+	set, err := Parse(strings.NewReader(src), "test.pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, curl := set["ring"], set["curl"]
+	if len(set) != 2 || ring == nil || curl == nil {
+		t.Fatalf("actions are %v", set)
+	}
+	if len(ring.Commands) != 3 {
+		t.Fatalf("ring has %d commands, want 3", len(ring.Commands))
+	}
+	repeat := ring.Commands[0]
+	if repeat.Op != Repeat || repeat.Count != 12 || repeat.Line != 3 || len(repeat.Body) != 1 {
+		t.Errorf("repeat is %+v", repeat)
+	}
+	fire := repeat.Body[0]
+	if fire.Op != Fire || fire.Dir.Mode != Sequence || !near(fire.Dir.Value, math.Pi/6) || fire.Speed != (Value{Absolute, 0.8}) {
+		t.Errorf("fire in the repeat is %+v", fire)
+	}
+	if wait := ring.Commands[1]; wait.Op != Wait || wait.Count != 30 {
+		t.Errorf("wait is %+v", wait)
+	}
+	aimed := ring.Commands[2]
+	if aimed.Dir != (Value{Aim, 0}) || aimed.Speed != (Value{Absolute, 1}) || aimed.Bullet != curl {
+		t.Errorf("the aimed fire is %+v", aimed)
+	}
+	if dir := curl.Commands[0]; dir.Op != Direction || dir.Dir.Mode != Relative || !near(dir.Dir.Value, -math.Pi/2) || dir.Count != 20 {
+		t.Errorf("direction is %+v", dir)
+	}
+	if speed := curl.Commands[1]; speed.Op != Speed || speed.Speed != (Value{Sequence, 0.5}) || speed.Count != 10 {
+		t.Errorf("speed is %+v", speed)
+	}
+	if curl.Commands[2].Op != Vanish {
+		t.Errorf("the last command of curl is %+v", curl.Commands[2])
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"fire", 1, "expected: action <name>"},
+		{"action a\nend\naction a\nend", 3, `action "a" is already defined`},
+		{"action a\nend now", 2, `unexpected "now" after end`},
+		{"action a\n  repeat 2\n  fire\nend", 1, "missing end"},
+		{"action a\n  repeat 2\n  fire", 2, "missing end"},
+		{"action a\n  fire dir=up\nend", 2, `invalid value "up"`},
+		{"action a\n  fire dir=seq10\nend", 2, `invalid value "seq10", expected a sign after "seq"`},
+		{"action a\n  fire size=2\nend", 2, `unknown option "size=2"`},
+		{"action a\n  wait\nend", 2, "usage: wait <count>"},
+		{"action a\n  repeat 0\n  end\nend", 2, `invalid count "0"`},
+		{"action a\n  speed 1\nend", 2, "usage: speed <value> <ticks>"},
+		{"action a\n  direction 90 x\nend", 2, `invalid number of ticks "x"`},
+		{"action a\n  vanish now\nend", 2, "vanish takes no arguments"},
+		{"action a\n  jump\nend", 2, `unknown command "jump"`},
+		{"action a\n\n  repeat 2\n    fire bullet=nope\n  end\nend", 4, `unknown bullet action "nope"`},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.pat")
+		textfile.Check(t, err, "bad.pat", tt.line, tt.msg)
+	}
+}
+
+// TestLoadDir loads the patterns that ship with the game, where bullet
+// actions may be in another file than the actions that fire them
+func TestLoadDir(t *testing.T) {
+	set, err := LoadDir("../patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) == 0 {
+		t.Fatal("no patterns")
+	}
+	for name, action := range set {
+		if action.Name != name || action.Filename == "" {
+			t.Errorf("action %s is %+v", name, action)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package pattern runs bullet patterns, described in a small language that
+// is inspired by BulletML. A pattern file contains named actions:
+//
+//	# a ring of 12 bullets, then three aimed bullets
+//	action ring
+//	  repeat 12
+//	    fire dir=seq+30 speed=0.8
+//	  end
+//	  wait 30
+//	  repeat 3
+//	    fire dir=aim speed=1.5 bullet=curl
+//	    wait 5
+//	  end
+//	end
+//
+//	# actions that are attached to bullets control the bullet that runs them
+//	action curl
+//	  wait 20
+//	  direction seq+3 30
+//	  speed 2 20
+//	end
+//
+// The commands are:
+//
+//	fire [dir=D] [speed=S] [bullet=action]  fire a bullet, optionally running an action
+//	repeat N ... end                        run the commands in between N times
+//	wait N                                  wait N ticks
+//	direction D N                           turn to D over N ticks
+//	speed S N                               change speed to S over N ticks
+//	vanish                                  remove the bullet that runs the action
+//
+// Directions are in degrees, where 0 is right and 90 is down. A direction is
+// absolute ("90"), towards the player ("aim", "aim+10"), relative to the
+// previously fired bullet ("seq+10") or relative to the direction of the
+// bullet running the action ("rel+10"). For "direction", "seq+10" turns by
+// 10 degrees every tick. Speeds are in pixels per tick and are absolute
+// ("1.5"), relative to the previous bullet ("seq+0.1") or relative to the
+// running bullet ("rel+0.5").
+package pattern
+
+// Op is a pattern command
+type Op uint8
+
+const (
+	Fire Op = iota
+	Repeat
+	Wait
+	Direction
+	Speed
+	Vanish
+)
+
+// Mode says what a direction or speed is relative to
+type Mode uint8
+
+const (
+	Absolute Mode = iota
+	Aim
+	Sequence
+	Relative
+)
+
+// Value is a direction or speed, together with what it is relative to
+type Value struct {
+	Mode  Mode
+	Value float64
+}
+
+// Command is a single command in an action
+type Command struct {
+	Op     Op
+	Line   int
+	Dir    Value
+	Speed  Value
+	Bullet *Action   // Fire: the action the bullet runs, if any
+	Count  int       // Repeat: times to repeat, Wait, Direction, Speed: ticks
+	Body   []Command // Repeat: the commands to repeat
+	bullet string    // Fire: the name of Bullet, until it has been resolved
+}
+
+// Action is a named list of commands
+type Action struct {
+	Name     string
+	Filename string
+	Commands []Command
+}
+
+// Set is a collection of actions, by name
+type Set map[string]*Action
@@ -0,0 +1,175 @@
+package pattern
+
+import (
+	"encoding/binary"
+	"hash"
+	"math"
+)
+
+// Env is what a running action sees of the world
+type Env interface {
+	// Aim returns the direction from the runner towards the player
+	Aim() float64
+	// Fire fires a bullet from the runner
+	Fire(dir, speed float64, bullet *Action)
+}
+
+// loop is a list of commands that is being run, and how many more times
+type loop struct {
+	commands []Command
+	pc       int
+	left     int
+}
+
+// Runner runs an action, one tick at a time. Dir and Speed belong to the
+// bullet or emitter that runs the action, and are changed by the direction
+// and speed commands.
+type Runner struct {
+	Dir      float64
+	Speed    float64
+	Vanished bool
+
+	stack     []loop
+	wait      int
+	lastDir   float64 // direction of the previously fired bullet
+	lastSpeed float64 // speed of the previously fired bullet
+	turn      float64 // change in direction per tick
+	turnLeft  int
+	accel     float64 // change in speed per tick
+	accelLeft int
+}
+
+// NewRunner returns a runner that starts at the beginning of the action
+func NewRunner(a *Action, dir, speed float64) *Runner {
+	return &Runner{
+		Dir:       dir,
+		Speed:     speed,
+		stack:     []loop{{a.Commands, 0, 1}},
+		lastDir:   dir,
+		lastSpeed: speed,
+	}
+}
+
+// Restart runs the action again from the start. The direction and speed of
+// the previously fired bullet are kept, so that sequences carry on.
+func (r *Runner) Restart(a *Action) {
+	r.stack = append(r.stack[:0], loop{a.Commands, 0, 1})
+	r.wait = 0
+}
+
+// Done returns true when the action has no more commands to run
+func (r *Runner) Done() bool {
+	return len(r.stack) == 0 && r.wait == 0
+}
+
+// Clone returns a copy of the runner, that can be stepped independently
+func (r *Runner) Clone() *Runner {
+	c := *r
+	c.stack = append([]loop(nil), r.stack...)
+	return &c
+}
+
+// Step advances the action by one tick
+func (r *Runner) Step(env Env) {
+	if r.turnLeft > 0 {
+		r.Dir += r.turn
+		r.turnLeft--
+	}
+	if r.accelLeft > 0 {
+		r.Speed += r.accel
+		r.accelLeft--
+	}
+	if r.wait > 0 {
+		r.wait--
+		if r.wait > 0 {
+			return
+		}
+	}
+	for len(r.stack) > 0 && !r.Vanished {
+		top := &r.stack[len(r.stack)-1]
+		if top.pc >= len(top.commands) {
+			top.left--
+			if top.left > 0 {
+				top.pc = 0
+			} else {
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+			continue
+		}
+		cmd := &top.commands[top.pc]
+		top.pc++
+		switch cmd.Op {
+		case Fire:
+			dir := r.direction(cmd.Dir, env)
+			speed := r.speed(cmd.Speed)
+			r.lastDir, r.lastSpeed = dir, speed
+			env.Fire(dir, speed, cmd.Bullet)
+		case Repeat:
+			// top is not valid after the append
+			r.stack = append(r.stack, loop{cmd.Body, 0, cmd.Count})
+		case Wait:
+			r.wait = cmd.Count
+			return
+		case Direction:
+			r.turnLeft = cmd.Count
+			if cmd.Dir.Mode == Sequence {
+				r.turn = cmd.Dir.Value
+			} else {
+				// take the shortest way around
+				r.turn = math.Remainder(r.direction(cmd.Dir, env)-r.Dir, 2*math.Pi) / float64(cmd.Count)
+			}
+		case Speed:
+			r.accelLeft = cmd.Count
+			if cmd.Speed.Mode == Sequence {
+				r.accel = cmd.Speed.Value
+			} else {
+				r.accel = (r.speed(cmd.Speed) - r.Speed) / float64(cmd.Count)
+			}
+		case Vanish:
+			r.Vanished = true
+		}
+	}
+}
+
+func (r *Runner) direction(v Value, env Env) float64 {
+	switch v.Mode {
+	case Aim:
+		return env.Aim() + v.Value
+	case Sequence:
+		return r.lastDir + v.Value
+	case Relative:
+		return r.Dir + v.Value
+	}
+	return v.Value
+}
+
+func (r *Runner) speed(v Value) float64 {
+	switch v.Mode {
+	case Sequence:
+		return r.lastSpeed + v.Value
+	case Relative:
+		return r.Speed + v.Value
+	}
+	return v.Value
+}
+
+// Hash writes the state of the runner to h, for checksums of the game state
+func (r *Runner) Hash(h hash.Hash) {
+	var buf []byte
+	for _, f := range []float64{r.Dir, r.Speed, r.lastDir, r.lastSpeed, r.turn, r.accel} {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+	}
+	for _, n := range []int{r.wait, r.turnLeft, r.accelLeft, len(r.stack)} {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(n))
+	}
+	for _, l := range r.stack {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(l.pc))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(l.left))
+	}
+	if r.Vanished {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	h.Write(buf)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Effect is a single post-processing pass, compiled from a .kage file
+type Effect struct {
+	Name      string
+	Enabled   bool
+	Intensity float32 // decays towards 0 every tick, see Kick
+	shader    *ebiten.Shader
+}
+
+// PostFX is an ordered chain of effects that is applied to the final frame
+type PostFX struct {
+	Effects []*Effect
+	scene   *ebiten.Image
+	buffers [2]*ebiten.Image
+	time    float32
+}
+
+// loadPostFX compiles every .kage file in dir. The effects are applied in
+// filename order, and the name of an effect is the filename without the
+// numeric prefix and extension, so "40_crt.kage" becomes "crt".
+func loadPostFX(dir string) (*PostFX, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.kage"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+	fx := &PostFX{
+		scene:   ebiten.NewImage(W, H),
+		buffers: [2]*ebiten.Image{ebiten.NewImage(W, H), ebiten.NewImage(W, H)},
+	}
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		shader, err := ebiten.NewShader(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		fx.Effects = append(fx.Effects, &Effect{Name: effectName(filename), shader: shader})
+	}
+	return fx, nil
+}
+
+func effectName(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), ".kage")
+	if i := strings.IndexByte(name, '_'); i >= 0 && strings.Trim(name[:i], "0123456789") == "" {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// Effect returns the effect with the given name, or nil
+func (fx *PostFX) Effect(name string) *Effect {
+	for _, e := range fx.Effects {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Kick sets the intensity of an effect, for effects that react to events, like hits
+func (fx *PostFX) Kick(name string, intensity float32) {
+	if e := fx.Effect(name); e != nil && intensity > e.Intensity {
+		e.Intensity = intensity
+	}
+}
+
+// Update advances the shader clock and lets the effect intensities fade out
+func (fx *PostFX) Update() {
+	fx.time += 1.0 / float32(ebiten.TPS())
+	for _, e := range fx.Effects {
+		e.Intensity *= 0.9
+		if e.Intensity < 0.01 {
+			e.Intensity = 0
+		}
+	}
+}
+
+// Active returns true if at least one effect is enabled
+func (fx *PostFX) Active() bool {
+	for _, e := range fx.Effects {
+		if e.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Scene returns a cleared offscreen image that the frame should be drawn to
+// before calling Apply
+func (fx *PostFX) Scene() *ebiten.Image {
+	fx.scene.Clear()
+	return fx.scene
+}
+
+// Apply runs the scene image through all enabled effects and draws the result
+// to screen, moved by dx, dy for screen shake
+func (fx *PostFX) Apply(screen *ebiten.Image, dx, dy float64) {
+	src := fx.scene
+	var enabled []*Effect
+	for _, e := range fx.Effects {
+		if e.Enabled {
+			enabled = append(enabled, e)
+		}
+	}
+	if len(enabled) == 0 {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(dx, dy)
+		screen.DrawImage(src, op)
+		return
+	}
+	for i, e := range enabled {
+		dst := screen
+		op := &ebiten.DrawRectShaderOptions{}
+		if i < len(enabled)-1 {
+			dst = fx.buffers[i%2]
+			dst.Clear()
+		} else {
+			op.GeoM.Translate(dx, dy)
+		}
+		op.Images[0] = src
+		op.Uniforms = map[string]any{
+			"Time":      fx.time,
+			"Intensity": e.Intensity,
+		}
+		dst.DrawRectShader(W, H, e.shader, op)
+		src = dst
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEffectName(t *testing.T) {
+	for _, tt := range []struct{ filename, name string }{
+		{"shaders/40_crt.kage", "crt"},
+		{"shaders/crt.kage", "crt"},
+		{"shaders/10_soft_glow.kage", "soft_glow"},
+		{"shaders/v2_crt.kage", "v2_crt"},
+	} {
+		if got := effectName(tt.filename); got != tt.name {
+			t.Errorf("%s is called %q, want %q", tt.filename, got, tt.name)
+		}
+	}
+}
+
+// TestPostFX loads the effects that ship with the game, and checks that
+// they are in filename order and that a kick fades out
+func TestPostFX(t *testing.T) {
+	fx, err := loadPostFX("shaders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range fx.Effects {
+		names = append(names, e.Name)
+	}
+	if want := []string{"bloom", "chromatic", "palette", "crt"}; !slices.Equal(names, want) {
+		t.Errorf("the effects are %v, want %v", names, want)
+	}
+	if fx.Active() {
+		t.Error("an effect is enabled before the settings are applied")
+	}
+	fx.Effect("crt").Enabled = true
+	if !fx.Active() || fx.Effect("vhs") != nil {
+		t.Error("the crt effect is not enabled, or there is a vhs effect")
+	}
+	fx.Kick("chromatic", 1)
+	fx.Kick("chromatic", 0.5)
+	e := fx.Effect("chromatic")
+	if e.Intensity != 1 {
+		t.Errorf("a weaker kick set the intensity to %g", e.Intensity)
+	}
+	for range 60 {
+		fx.Update()
+	}
+	if e.Intensity != 0 {
+		t.Errorf("the intensity is %g a second after the kick", e.Intensity)
+	}
+}
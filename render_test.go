@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hi/bot"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var update = flag.Bool("update", false, "write new golden images instead of comparing against them")
+
+const (
+	// GOLDEN is where the golden images are
+	GOLDEN = "testdata/golden"
+	// TOLERANCE is how much a color channel may differ, from 0 to 255
+	TOLERANCE = 8
+	// MAX_DIFF is the fraction of pixels that may differ by more than the tolerance
+	MAX_DIFF = 0.001
+)
+
+// Scene is a frame that is rendered and compared against a golden image
+type Scene struct {
+	Name    string
+	Ticks   uint64 // ticks to play, with the bot, before drawing
+	Players int
+	Setup   func() // turns on overlays and effects, after the world is made
+}
+
+// scenes are the frames that TestRender draws. Every scene starts from the
+// first level with seed 1, and the bot playing.
+var scenes = []Scene{
+	{Name: "start", Ticks: 60, Players: 1},
+	{Name: "wave", Ticks: 10 * 60, Players: 1},
+	{Name: "coop", Ticks: 20 * 60, Players: 2},
+	{Name: "boss", Ticks: 40 * 60, Players: 1},
+	{Name: "debug", Ticks: 10 * 60, Players: 1, Setup: func() { debug = true }},
+	{Name: "console", Ticks: 60, Players: 1, Setup: func() {
+		console.Open = true
+		console.run("help")
+	}},
+	{Name: "options", Ticks: 60, Players: 1, Setup: func() { options.Open = true }},
+	{Name: "attract", Ticks: 5 * 60, Players: 1, Setup: func() { attract = bot.New(0) }},
+	{Name: "effects", Ticks: 10 * 60, Players: 1, Setup: func() {
+		for _, e := range postFX.Effects {
+			e.Enabled = true
+		}
+	}},
+	{Name: "access", Ticks: 10 * 60, Players: 1, Setup: func() {
+		settings.Access.Palette = "deuteranopia"
+		settings.Access.HighContrast = true
+	}},
+}
+
+// TestMain runs the tests inside the game loop, since drawing needs the GPU.
+// It opens a window for a moment.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	g := &testGame{m: m, done: make(chan struct{})}
+	ebiten.SetWindowTitle("hi test")
+	ebiten.SetWindowSize(W, H)
+	if err := ebiten.RunGame(g); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(g.code)
+}
+
+// testGame runs the tests on a goroutine once the game loop is going, and
+// quits when they are done
+type testGame struct {
+	m       *testing.M
+	started bool
+	code    int
+	done    chan struct{}
+}
+
+func (g *testGame) Update() error {
+	if !g.started {
+		g.started = true
+		go func() {
+			g.code = g.m.Run()
+			close(g.done)
+		}()
+	}
+	select {
+	case <-g.done:
+		return ebiten.Termination
+	default:
+		return nil
+	}
+}
+
+func (g *testGame) Draw(screen *ebiten.Image) {}
+
+func (g *testGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return W, H
+}
+
+// TestRender draws every scene offscreen and compares it against its golden
+// image, so that changes to sprites, the HUD and the effects are noticed.
+// After a change that is meant to look different, go test -run TestRender
+// -update writes new golden images.
+func TestRender(t *testing.T) {
+	if err := loadData(); err != nil {
+		t.Fatal(err)
+	}
+	// the options menu points into the settings, so they come first
+	settings = defaultSettings()
+	settings.Gameplay.Shake = 0
+	settings.Language = BASE_LANGUAGE
+	if err := loadResources(); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll(GOLDEN, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frame := ebiten.NewImage(W, H)
+	for _, scene := range scenes {
+		t.Run(scene.Name, func(t *testing.T) {
+			if err := scene.play(); err != nil {
+				t.Fatal(err)
+			}
+			frame.Clear()
+			(&Game{}).Draw(frame)
+			img := image.NewRGBA(image.Rect(0, 0, W, H))
+			frame.ReadPixels(img.Pix)
+			filename := filepath.Join(GOLDEN, scene.Name+".png")
+			if *update {
+				if err := savePNG(filename, img); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err := compare(filename, img); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// play resets the globals that Draw looks at, and plays the scene up to the frame
+func (s *Scene) play() error {
+	w, err := sim.LoadLevel("levels/1.lvl", 1, sim.DefaultDifficulty)
+	if err != nil {
+		return err
+	}
+	world, debug, attract, shake = w, false, nil, 0
+	options.Open, console, clock, history = false, &Console{}, &Clock{Scale: 1}, &History{}
+	for _, e := range postFX.Effects {
+		e.Enabled, e.Intensity = false, 0
+	}
+	settings.Access.Palette, settings.Access.HighContrast = "normal", false
+	bots := make([]*bot.Bot, s.Players)
+	inputs := make([]sim.Input, s.Players)
+	for i := range bots {
+		bots[i] = bot.New(i)
+		inputs[i] = sim.Join
+	}
+	world.Step(inputs)
+	for world.Tick < s.Ticks {
+		for i, b := range bots {
+			inputs[i] = b.Input(world)
+		}
+		world.Step(inputs)
+	}
+	if s.Setup != nil {
+		s.Setup()
+	}
+	return nil
+}
+
+// compare compares a frame against a golden image. When too many pixels
+// differ, the frame is saved next to it as <scene>.actual.png.
+func compare(filename string, img *image.RGBA) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("%w, run go test -run TestRender -update to make it", err)
+	}
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		return fmt.Errorf("%s: the golden image is %v, not %dx%d", filename, decoded.Bounds().Size(), W, H)
+	}
+	golden := image.NewRGBA(decoded.Bounds())
+	draw.Draw(golden, golden.Bounds(), decoded, image.Point{}, draw.Src)
+	diff := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 4; c++ {
+			if d := int(img.Pix[i+c]) - int(golden.Pix[i+c]); d > TOLERANCE || -d > TOLERANCE {
+				diff++
+				break
+			}
+		}
+	}
+	if float64(diff) > MAX_DIFF*W*H {
+		actual := filename[:len(filename)-len(".png")] + ".actual.png"
+		if err := savePNG(actual, img); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: %d pixels differ, see %s", filename, diff, actual)
+	}
+	return nil
+}
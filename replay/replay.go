@@ -0,0 +1,291 @@
+// Package replay records the inputs of a game, so that it can be played
+// back exactly, and checked against the hashes of the world state.
+//
+// A replay file is plain text, with the level, seed and difficulty first,
+// and then the inputs of every player as hexadecimal numbers, with how many ticks
+// in a row they were held:
+//
+//	hi replay 1
+//	level levels/1.lvl
+//	seed 1234
+//	difficulty hard adaptive
+//	input 120 0 0 0 0
+//	input 3 12 0 0 0
+//	hash 600 9f3c4b2d1a0e8f76
+//
+// Every HASH_EVERY ticks, and at the end, a hash of the world state is
+// written, so that a replay that plays out differently is caught at the
+// first hash that does not match. Replays without a difficulty were
+// recorded before there were any, and are played on the default one.
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"hi/sim"
+	"hi/textfile"
+)
+
+// HEADER is the first line of every replay file
+const HEADER = "hi replay 1"
+
+// HASH_EVERY is the number of ticks between hashes of the world state
+const HASH_EVERY = 600
+
+// Frame is the input of every player for one tick
+type Frame [sim.MAX_PLAYERS]sim.Input
+
+// Hash is a checksum of the world state after a given tick
+type Hash struct {
+	Tick uint64
+	Sum  uint64
+}
+
+// Replay is a recorded game
+type Replay struct {
+	Filename   string
+	Level      string
+	Seed       uint64
+	Difficulty sim.Difficulty
+	Frames     []Frame
+	Hashes     []Hash
+}
+
+// New returns an empty replay of the given level, seed and difficulty
+func New(level string, seed uint64, difficulty sim.Difficulty) *Replay {
+	return &Replay{Level: level, Seed: seed, Difficulty: difficulty}
+}
+
+// Add records the inputs for a tick and steps the world with them,
+// hashing the world when it is time to
+func (r *Replay) Add(w *sim.World, inputs []sim.Input) {
+	var f Frame
+	copy(f[:], inputs)
+	r.Frames = append(r.Frames, f)
+	w.Step(f[:])
+	if len(r.Frames)%HASH_EVERY == 0 {
+		r.Hashes = append(r.Hashes, Hash{w.Tick, w.Hash()})
+	}
+}
+
+// Finish adds the final hash, if the replay did not end on one
+func (r *Replay) Finish(w *sim.World) {
+	if n := len(r.Hashes); n == 0 || r.Hashes[n-1].Tick != w.Tick {
+		r.Hashes = append(r.Hashes, Hash{w.Tick, w.Hash()})
+	}
+}
+
+// Truncate drops the frames and hashes after tick, so that recording can go
+// on from there after rewinding
+func (r *Replay) Truncate(tick uint64) {
+	r.Frames = r.Frames[:min(int(tick), len(r.Frames))]
+	for len(r.Hashes) > 0 && r.Hashes[len(r.Hashes)-1].Tick > tick {
+		r.Hashes = r.Hashes[:len(r.Hashes)-1]
+	}
+}
+
+// Player plays back a replay, one tick at a time
+type Player struct {
+	Replay *Replay
+	next   int // the next frame
+	hash   int // the next hash to check
+}
+
+// Step steps the world with the next frame of the replay, and returns false
+// when the replay is over. The world is hashed and checked along the way.
+func (p *Player) Step(w *sim.World) (bool, error) {
+	if p.next >= len(p.Replay.Frames) {
+		return false, nil
+	}
+	f := p.Replay.Frames[p.next]
+	w.Step(f[:])
+	p.next++
+	for p.hash < len(p.Replay.Hashes) && p.Replay.Hashes[p.hash].Tick <= w.Tick {
+		h := p.Replay.Hashes[p.hash]
+		p.hash++
+		if h.Tick == w.Tick && h.Sum != w.Hash() {
+			return true, fmt.Errorf("%s: the game went out of sync before tick %d", p.Replay.Filename, w.Tick)
+		}
+	}
+	return true, nil
+}
+
+// Seek makes the next Step play the frame for tick, after the world was
+// rewound to it
+func (p *Player) Seek(tick uint64) {
+	p.next = min(int(tick), len(p.Replay.Frames))
+	p.hash = 0
+	for p.hash < len(p.Replay.Hashes) && p.Replay.Hashes[p.hash].Tick <= tick {
+		p.hash++
+	}
+}
+
+// Verify plays the whole replay on w, which must be a new world with the
+// level and seed of the replay
+func (r *Replay) Verify(w *sim.World) error {
+	p := &Player{Replay: r}
+	for {
+		more, err := p.Step(w)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// Load reads and parses a replay file
+func Load(filename string) (*Replay, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads a replay from rd. The filename is only used for error messages.
+func Parse(rd io.Reader, filename string) (*Replay, error) {
+	r := &Replay{Filename: filename, Difficulty: sim.DefaultDifficulty}
+	scanner := bufio.NewScanner(rd)
+	line := 0
+	errorf := func(format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			if text != HEADER {
+				return nil, errorf("not a replay file, expected %q", HEADER)
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "level":
+			if len(fields) != 2 {
+				return nil, errorf("usage: level <filename>")
+			}
+			r.Level = fields[1]
+		case "seed":
+			if len(fields) != 2 {
+				return nil, errorf("usage: seed <number>")
+			}
+			seed, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, errorf("invalid seed %q", fields[1])
+			}
+			r.Seed = seed
+		case "difficulty":
+			if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != "adaptive") {
+				return nil, errorf("usage: difficulty <name> [adaptive]")
+			}
+			d, err := sim.FindDifficulty(fields[1])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			d.Adaptive = len(fields) == 3
+			r.Difficulty = d
+		case "input":
+			if len(fields) < 2 || len(fields) > 2+sim.MAX_PLAYERS {
+				return nil, errorf("usage: input <ticks> <input of player 1> ...")
+			}
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 {
+				return nil, errorf("invalid number of ticks %q", fields[1])
+			}
+			var f Frame
+			for i, s := range fields[2:] {
+				in, err := strconv.ParseUint(s, 16, 16)
+				if err != nil {
+					return nil, errorf("invalid input %q", s)
+				}
+				f[i] = sim.Input(in)
+			}
+			for ; n > 0; n-- {
+				r.Frames = append(r.Frames, f)
+			}
+		case "hash":
+			if len(fields) != 3 {
+				return nil, errorf("usage: hash <tick> <hash>")
+			}
+			tick, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, errorf("invalid tick %q", fields[1])
+			}
+			sum, err := strconv.ParseUint(fields[2], 16, 64)
+			if err != nil {
+				return nil, errorf("invalid hash %q", fields[2])
+			}
+			r.Hashes = append(r.Hashes, Hash{tick, sum})
+		default:
+			return nil, errorf("unknown directive %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, errorf("empty replay file")
+	}
+	if r.Level == "" {
+		return nil, errorf("the replay does not say which level it is for")
+	}
+	return r, nil
+}
+
+// Save writes the replay to a file
+func (r *Replay) Save(filename string) error {
+	r.Filename = filename
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the replay in the replay file format
+func (r *Replay) Write(wr io.Writer) error {
+	w := bufio.NewWriter(wr)
+	fmt.Fprintln(w, HEADER)
+	fmt.Fprintf(w, "level %s\n", r.Level)
+	fmt.Fprintf(w, "seed %d\n", r.Seed)
+	fmt.Fprintf(w, "difficulty %s", r.Difficulty.Name)
+	if r.Difficulty.Adaptive {
+		fmt.Fprint(w, " adaptive")
+	}
+	fmt.Fprintln(w)
+	// the hashes are written after the input for the tick they were taken at
+	hashes := r.Hashes
+	for i := 0; i < len(r.Frames); {
+		n := 1
+		for i+n < len(r.Frames) && r.Frames[i+n] == r.Frames[i] && (len(hashes) == 0 || uint64(i+n) < hashes[0].Tick) {
+			n++
+		}
+		fmt.Fprintf(w, "input %d", n)
+		for _, in := range r.Frames[i] {
+			fmt.Fprintf(w, " %x", uint16(in))
+		}
+		fmt.Fprintln(w)
+		i += n
+		for len(hashes) > 0 && hashes[0].Tick <= uint64(i) {
+			fmt.Fprintf(w, "hash %d %016x\n", hashes[0].Tick, hashes[0].Sum)
+			hashes = hashes[1:]
+		}
+	}
+	for _, h := range hashes {
+		fmt.Fprintf(w, "hash %d %016x\n", h.Tick, h.Sum)
+	}
+	return w.Flush()
+}
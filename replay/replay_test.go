@@ -0,0 +1,134 @@
+package replay
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/sim"
+	"hi/textfile"
+)
+
+// newWorld returns a new world for the level, seed and difficulty of a
+// replay. The level filenames in replays are relative to the top of the
+// repository.
+func newWorld(t *testing.T, r *Replay) *sim.World {
+	t.Helper()
+	if err := sim.LoadData(".."); err != nil {
+		t.Fatal(err)
+	}
+	w, err := sim.LoadLevel(filepath.Join("..", r.Level), r.Seed, r.Difficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// record plays ticks with inputs that change every few ticks, and returns the replay
+func record(t *testing.T, difficulty sim.Difficulty, ticks int) *Replay {
+	t.Helper()
+	r := New("levels/1.lvl", 99, difficulty)
+	w := newWorld(t, r)
+	r.Add(w, []sim.Input{sim.Join, sim.Join})
+	for i := 1; i < ticks; i++ {
+		in := sim.Input(i/7*2654435761) & (sim.Left | sim.Right | sim.Up | sim.Down | sim.Fire)
+		r.Add(w, []sim.Input{in, in ^ sim.Fire})
+	}
+	r.Finish(w)
+	return r
+}
+
+func TestRoundTrip(t *testing.T) {
+	hard, _ := sim.FindDifficulty("hard")
+	hard.Adaptive = true
+	for _, d := range []sim.Difficulty{sim.DefaultDifficulty, hard} {
+		r := record(t, d, 1000)
+		var buf bytes.Buffer
+		if err := r.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(&buf, "test.rep")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Level != r.Level || got.Seed != r.Seed || got.Difficulty != r.Difficulty {
+			t.Errorf("%s: read back level %s, seed %d and %+v", d.Name, got.Level, got.Seed, got.Difficulty)
+		}
+		if !slices.Equal(got.Frames, r.Frames) {
+			t.Errorf("%s: read back %d frames, want %d, or they differ", d.Name, len(got.Frames), len(r.Frames))
+		}
+		// one hash after 600 ticks, and one at the end
+		if !slices.Equal(got.Hashes, r.Hashes) || len(got.Hashes) != 2 {
+			t.Errorf("%s: read back the hashes %v, want %v", d.Name, got.Hashes, r.Hashes)
+		}
+		if err := got.Verify(newWorld(t, got)); err != nil {
+			t.Errorf("%s: %v", d.Name, err)
+		}
+	}
+}
+
+// TestOutOfSync checks that a replay that plays out differently is caught
+func TestOutOfSync(t *testing.T) {
+	r := record(t, sim.DefaultDifficulty, 1000)
+	r.Filename = "test.rep"
+	r.Frames[300][0] ^= sim.Left | sim.Bomb
+	err := r.Verify(newWorld(t, r))
+	if err == nil || err.Error() != "test.rep: the game went out of sync before tick 600" {
+		t.Errorf("got %v", err)
+	}
+}
+
+// TestBeforeDifficulty plays back a replay that was recorded before there
+// were difficulties, which must still play out the same on the default one
+func TestBeforeDifficulty(t *testing.T) {
+	r, err := Load("testdata/before-difficulty.rep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Difficulty != sim.DefaultDifficulty {
+		t.Errorf("the difficulty is %+v, want the default", r.Difficulty)
+	}
+	if err := r.Verify(newWorld(t, r)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	r := record(t, sim.DefaultDifficulty, 1000)
+	r.Truncate(700)
+	if len(r.Frames) != 700 || len(r.Hashes) != 1 || r.Hashes[0].Tick != 600 {
+		t.Errorf("%d frames and the hashes %v are left", len(r.Frames), r.Hashes)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const head = HEADER + "\nlevel levels/1.lvl\n"
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"hi replay 0\n", 1, `not a replay file, expected "hi replay 1"`},
+		{"", 0, "empty replay file"},
+		{HEADER + "\nseed 1\n", 2, "the replay does not say which level it is for"},
+		{HEADER + "\nlevel\n", 2, "usage: level <filename>"},
+		{head + "seed x", 3, `invalid seed "x"`},
+		{head + "seed", 3, "usage: seed <number>"},
+		{head + "difficulty", 3, "usage: difficulty <name> [adaptive]"},
+		{head + "difficulty hard fast", 3, "usage: difficulty <name> [adaptive]"},
+		{head + "difficulty brutal", 3, `unknown difficulty "brutal"`},
+		{head + "\n# comment\ninput 0 1", 5, `invalid number of ticks "0"`},
+		{head + "input", 3, "usage: input <ticks> <input of player 1> ..."},
+		{head + "input 1 0 0 0 0 0", 3, "usage: input <ticks> <input of player 1> ..."},
+		{head + "input 1 zz", 3, `invalid input "zz"`},
+		{head + "hash 600", 3, "usage: hash <tick> <hash>"},
+		{head + "hash x 0", 3, `invalid tick "x"`},
+		{head + "hash 600 xyz", 3, `invalid hash "xyz"`},
+		{head + "pause 1", 3, `unknown directive "pause"`},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.rep")
+		textfile.Check(t, err, "bad.rep", tt.line, tt.msg)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"hi/mixer"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Settings are the options that are kept between runs, in settings.json
+// in the user config directory. They are changed in the options menu.
+type Settings struct {
+	Language string // the code of the language, or "" for the language of the system
+	Video    struct {
+		Scale      int
+		Fullscreen bool
+		VSync      bool
+		Effects    map[string]bool // post-processing effects that are enabled, by name
+	}
+	Audio struct {
+		Master  float64 // volumes, from 0 to 1
+		Music   float64
+		Effects float64
+		UI      float64
+	}
+	Controls struct {
+		Keyboard1 Keyboard
+		Keyboard2 Keyboard
+	}
+	Gameplay struct {
+		Difficulty string
+		Adaptive   bool    // the game gets easier or harder with how well the players do
+		Shake      float64 // how much the screen shakes when the ship is hit, from 0 to 1
+	}
+	Access struct {
+		Palette       string  // the colors are drawn through this, for color vision deficiencies
+		HighContrast  bool    // enemy bullets are outlined
+		ReducedMotion bool    // no screen shake or flashes
+		HoldToFire    bool    // holding fire keeps firing, instead of a press per bullet
+		Speed         float64 // the game runs at this speed, from MIN_SPEED to 1
+	}
+}
+
+const MAX_SCALE = 6
+
+// difficulties are the names of the difficulty presets, from easiest to hardest
+var difficulties []string
+
+func init() {
+	for _, d := range sim.Difficulties {
+		difficulties = append(difficulties, d.Name)
+	}
+}
+
+var settings = defaultSettings()
+
+func defaultSettings() *Settings {
+	s := &Settings{}
+	s.Video.Scale = 2
+	s.Video.VSync = true
+	s.Video.Effects = make(map[string]bool)
+	s.Audio.Master, s.Audio.Music, s.Audio.Effects, s.Audio.UI = 1, 0.8, 1, 0.8
+	s.Controls.Keyboard1 = defaultKeyboards[0]
+	s.Controls.Keyboard2 = defaultKeyboards[1]
+	s.Gameplay.Difficulty = "normal"
+	s.Gameplay.Shake = 0.5
+	s.Access.Palette = "normal"
+	s.Access.Speed = 1
+	return s
+}
+
+// gameDifficulty returns the difficulty that new games are played on. It
+// is not changed in a game that has started, so that it plays out the same
+// way as its replay.
+func gameDifficulty() sim.Difficulty {
+	d, err := sim.FindDifficulty(settings.Gameplay.Difficulty)
+	if err != nil {
+		d = sim.DefaultDifficulty
+	}
+	d.Adaptive = settings.Gameplay.Adaptive
+	return d
+}
+
+// settingsFile returns where the settings are kept
+func settingsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hi", "settings.json"), nil
+}
+
+// loadSettings reads the settings file, if there is one. Settings that are
+// missing from the file keep their default values.
+func loadSettings() (*Settings, error) {
+	s := defaultSettings()
+	filename, err := settingsFile()
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return defaultSettings(), fmt.Errorf("%s: %w", filename, err)
+	}
+	if s.Video.Scale < 1 || s.Video.Scale > MAX_SCALE {
+		return defaultSettings(), fmt.Errorf("%s: the scale must be from 1 to %d, not %d", filename, MAX_SCALE, s.Video.Scale)
+	}
+	if _, err := sim.FindDifficulty(s.Gameplay.Difficulty); err != nil {
+		return defaultSettings(), fmt.Errorf("%s: %w", filename, err)
+	}
+	if _, err := findPalette(s.Access.Palette); err != nil {
+		return defaultSettings(), fmt.Errorf("%s: %w", filename, err)
+	}
+	for _, v := range []struct {
+		name  string
+		value float64
+	}{
+		{"master volume", s.Audio.Master},
+		{"music volume", s.Audio.Music},
+		{"effects volume", s.Audio.Effects},
+		{"UI volume", s.Audio.UI},
+		{"screen shake", s.Gameplay.Shake},
+	} {
+		if v.value < 0 || v.value > 1 {
+			return defaultSettings(), fmt.Errorf("%s: the %s must be from 0 to 1, not %g", filename, v.name, v.value)
+		}
+	}
+	if s.Access.Speed < MIN_SPEED || s.Access.Speed > 1 {
+		return defaultSettings(), fmt.Errorf("%s: the game speed must be from %g to 1, not %g", filename, MIN_SPEED, s.Access.Speed)
+	}
+	if s.Video.Effects == nil {
+		s.Video.Effects = make(map[string]bool)
+	}
+	return s, nil
+}
+
+// Save writes the settings to the settings file
+func (s *Settings) Save() error {
+	filename, err := settingsFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// Apply makes the settings take effect right away
+func (s *Settings) Apply() {
+	useLanguage(s.Language)
+	ebiten.SetWindowTitle(msg("window.title"))
+	ebiten.SetWindowSize(W*s.Video.Scale, H*s.Video.Scale)
+	ebiten.SetFullscreen(s.Video.Fullscreen)
+	ebiten.SetVsyncEnabled(s.Video.VSync)
+	if postFX != nil {
+		for _, e := range postFX.Effects {
+			e.Enabled = s.Video.Effects[e.Name]
+		}
+	}
+	s.applyAudio()
+	*keyboard1 = s.Controls.Keyboard1
+	*keyboard2 = s.Controls.Keyboard2
+}
+
+// applyAudio sets the volumes of the mixer
+func (s *Settings) applyAudio() {
+	mix.Master = s.Audio.Master
+	mix.Volumes[mixer.Music] = s.Audio.Music
+	mix.Volumes[mixer.SFX] = s.Audio.Effects
+	mix.Volumes[mixer.UI] = s.Audio.UI
+}
+
+// saveSettings saves the settings, and reports it if that fails, since the
+// game can go on without them
+func saveSettings() {
+	if err := settings.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
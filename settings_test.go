@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfigDir makes the settings file go in a new directory, and returns
+// where it is
+func useConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv("HOME", dir)
+	filename, err := settingsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	filename := useConfigDir(t)
+	for _, tt := range []struct {
+		json string
+		msg  string
+	}{
+		{`{"Audio": {"Master": 2}}`, "the master volume must be from 0 to 1, not 2"},
+		{`{"Audio": {"Effects": -0.5}}`, "the effects volume must be from 0 to 1, not -0.5"},
+		{`{"Gameplay": {"Shake": 10}}`, "the screen shake must be from 0 to 1, not 10"},
+		{`{"Video": {"Scale": 0}}`, "the scale must be from 1 to 6, not 0"},
+	} {
+		if err := os.WriteFile(filename, []byte(tt.json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := loadSettings()
+		if err == nil || !strings.HasSuffix(err.Error(), tt.msg) {
+			t.Errorf("%s: got %v, want %s", tt.json, err, tt.msg)
+		}
+		if s.Audio != defaultSettings().Audio || s.Gameplay != defaultSettings().Gameplay {
+			t.Errorf("%s: the settings are not the defaults", tt.json)
+		}
+	}
+}
+
+// TestSaveSettings saves settings and loads them back, and loads a file that
+// only has some of them
+func TestSaveSettings(t *testing.T) {
+	filename := useConfigDir(t)
+	s := defaultSettings()
+	s.Video.Scale = 3
+	s.Video.Effects["crt"] = true
+	s.Audio.Music = 0.25
+	s.Controls.Keyboard2.Fire = s.Controls.Keyboard1.Fire
+	s.Gameplay.Difficulty = "hard"
+	s.Access.Speed = 0.7
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Video.Scale != 3 || !loaded.Video.Effects["crt"] || loaded.Audio != s.Audio ||
+		loaded.Controls != s.Controls || loaded.Gameplay != s.Gameplay || loaded.Access != s.Access {
+		t.Errorf("saved %+v, loaded %+v", *s, *loaded)
+	}
+
+	if err := os.WriteFile(filename, []byte(`{"Audio": {"UI": 0.1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultSettings()
+	want.Audio.UI = 0.1
+	if loaded.Audio != want.Audio || loaded.Video.Scale != want.Video.Scale || loaded.Controls != want.Controls || loaded.Video.Effects == nil {
+		t.Errorf("the settings that are not in the file are not the defaults: %+v", *loaded)
+	}
+}
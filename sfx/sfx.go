@@ -0,0 +1,357 @@
+// Package sfx synthesises sound effects in the style of sfxr, from a few
+// numbers per sound. A sounds file has one sound per line, with the name,
+// the wave shape and the parameters that differ from the defaults:
+//
+//	# name  wave    parameters ...
+//	shoot   square  freq=1200 slide=-3 sustain=0.03 decay=0.08 duty=0.25
+//	boom    noise   freq=600 slide=-1 sustain=0.1 punch=0.6 decay=0.5 lowpass=3000
+//
+// The waves are square, saw, triangle, sine and noise. The parameters are:
+//
+//	volume=V      from 0 to 1, 0.5 by default
+//	attack=S      seconds to fade in
+//	sustain=S     seconds at full volume, 0.1 by default
+//	punch=P       extra volume at the start of the sustain, which fades out over it
+//	decay=S       seconds to fade out
+//	freq=F        the start frequency in Hz, 440 by default
+//	min=F         the sound stops when the frequency slides below this
+//	slide=O       frequency change, in octaves per second
+//	dslide=O      change of the slide, in octaves per second per second
+//	vibrato=D     depth of the vibrato, as a fraction of the frequency below 1
+//	vibspeed=F    speed of the vibrato in Hz
+//	arp=M         the frequency is multiplied by this once, after arptime seconds,
+//	              1 by default
+//	arptime=S
+//	duty=D        fraction of the period that a square wave is high, 0.5 by default
+//	dutysweep=D   change of the duty per second
+//	repeat=S      start the frequency, slide, arp and duty over every S seconds
+//	lowpass=F     cut off frequencies above F Hz
+//	highpass=F    cut off frequencies below F Hz
+//	seed=N        for the noise
+//
+// Sounds are generated the same way every time, including the noise.
+package sfx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"hi/textfile"
+)
+
+const (
+	// SAMPLE_RATE is the number of samples per second of generated sounds
+	SAMPLE_RATE = 44100
+
+	// MAX_SECONDS is the longest a sound can be
+	MAX_SECONDS = 5
+
+	// NOISE_STEPS is how many random values the noise has per period
+	NOISE_STEPS = 32
+)
+
+// Wave is the shape of the wave of a sound
+type Wave int
+
+const (
+	Square Wave = iota
+	Saw
+	Triangle
+	Sine
+	Noise
+)
+
+var waveNames = []string{"square", "saw", "triangle", "sine", "noise"}
+
+func (w Wave) String() string {
+	return waveNames[w]
+}
+
+// Params describe a sound, see the package documentation
+type Params struct {
+	Wave      Wave
+	Volume    float64
+	Attack    float64
+	Sustain   float64
+	Punch     float64
+	Decay     float64
+	Freq      float64
+	Min       float64
+	Slide     float64
+	DSlide    float64
+	Vibrato   float64
+	VibSpeed  float64
+	Arp       float64
+	ArpTime   float64
+	Duty      float64
+	DutySweep float64
+	Repeat    float64
+	Lowpass   float64
+	Highpass  float64
+	Seed      uint32
+}
+
+// Defaults returns the parameters of a sound that only sets the wave
+func Defaults(wave Wave) Params {
+	return Params{Wave: wave, Volume: 0.5, Duty: 0.5, Freq: 440, Sustain: 0.1, Arp: 1, Seed: 1}
+}
+
+// Length returns the length of the sound in seconds
+func (p *Params) Length() float64 {
+	return min(p.Attack+p.Sustain+p.Decay, MAX_SECONDS)
+}
+
+// Generate returns the samples of the sound, from -1 to 1, at SAMPLE_RATE
+func (p *Params) Generate() []float32 {
+	const dt = 1.0 / SAMPLE_RATE
+	samples := make([]float32, 0, int(p.Length()*SAMPLE_RATE))
+
+	var (
+		freq, slide, duty float64
+		arpDone           bool
+		phase             float64
+		since             float64 // seconds since the last repeat
+		low, high         float64 // filter state
+		noise             [NOISE_STEPS]float64
+		rand              = p.Seed | 1
+	)
+	start := func() {
+		freq, slide, duty, arpDone, since = p.Freq, p.Slide, p.Duty, false, 0
+	}
+	refill := func() {
+		for i := range noise {
+			// xorshift32
+			rand ^= rand << 13
+			rand ^= rand >> 17
+			rand ^= rand << 5
+			noise[i] = float64(rand)/(1<<31) - 1
+		}
+	}
+	start()
+	refill()
+	lowpass := 1 - math.Exp(-2*math.Pi*p.Lowpass*dt)
+	highpass := 1 - math.Exp(-2*math.Pi*p.Highpass*dt)
+
+	for i := 0; i < cap(samples); i++ {
+		t := float64(i) * dt
+		if p.Repeat > 0 && since >= p.Repeat {
+			start()
+		}
+		since += dt
+
+		freq *= math.Exp2(slide * dt)
+		slide += p.DSlide * dt
+		if p.Min > 0 && freq < p.Min {
+			break
+		}
+		if p.Arp != 1 && !arpDone && since >= p.ArpTime {
+			freq *= p.Arp
+			arpDone = true
+		}
+		duty = min(max(duty+p.DutySweep*dt, 0), 1)
+		f := freq
+		if p.Vibrato > 0 {
+			f *= 1 + p.Vibrato*math.Sin(2*math.Pi*p.VibSpeed*t)
+		}
+
+		phase += f * dt
+		if phase < 0 || phase >= 1 {
+			phase -= math.Floor(phase)
+			if p.Wave == Noise {
+				refill()
+			}
+		}
+
+		var v float64
+		switch p.Wave {
+		case Square:
+			v = 1
+			if phase >= duty {
+				v = -1
+			}
+		case Saw:
+			v = 1 - 2*phase
+		case Triangle:
+			v = 4*math.Abs(phase-0.5) - 1
+		case Sine:
+			v = math.Sin(2 * math.Pi * phase)
+		case Noise:
+			v = noise[int(phase*NOISE_STEPS)%NOISE_STEPS]
+		}
+
+		if p.Lowpass > 0 {
+			low += lowpass * (v - low)
+			v = low
+		}
+		if p.Highpass > 0 {
+			high += highpass * (v - high)
+			v -= high
+		}
+
+		v *= p.envelope(t) * p.Volume
+		samples = append(samples, float32(min(max(v, -1), 1)))
+	}
+	return samples
+}
+
+// envelope returns the volume at t seconds, from 0 to 1 plus the punch
+func (p *Params) envelope(t float64) float64 {
+	switch {
+	case t < p.Attack:
+		return t / p.Attack
+	case t < p.Attack+p.Sustain:
+		return 1 + p.Punch*(1-(t-p.Attack)/p.Sustain)
+	case p.Decay > 0:
+		return max(1-(t-p.Attack-p.Sustain)/p.Decay, 0)
+	}
+	return 0
+}
+
+// Sound is a sound from a sounds file
+type Sound struct {
+	Name     string
+	Filename string
+	Line     int
+	Params
+}
+
+// Sounds are sounds by name
+type Sounds map[string]*Sound
+
+// Load reads and parses a sounds file
+func Load(filename string) (Sounds, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, filename)
+}
+
+// Parse reads sounds from r. The filename is only used for error messages.
+func Parse(r io.Reader, filename string) (Sounds, error) {
+	sounds := make(Sounds)
+	errorf := func(line int, format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, errorf(line, "usage: <name> <wave> <parameter>=<value> ...")
+		}
+		name := fields[0]
+		if sounds[name] != nil {
+			return nil, errorf(line, "%s is already defined on line %d", name, sounds[name].Line)
+		}
+		params, err := parseParams(fields[1], fields[2:])
+		if err != nil {
+			return nil, errorf(line, "%v", err)
+		}
+		sounds[name] = &Sound{Name: name, Filename: filename, Line: line, Params: params}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sounds, nil
+}
+
+// parseParams parses a wave and the parameters that differ from its defaults
+func parseParams(waveName string, fields []string) (Params, error) {
+	wave := Wave(-1)
+	for i, n := range waveNames {
+		if waveName == n {
+			wave = Wave(i)
+		}
+	}
+	if wave < 0 {
+		return Params{}, fmt.Errorf("unknown wave %q, expected one of %s", waveName, strings.Join(waveNames, ", "))
+	}
+	p := Defaults(wave)
+	for _, f := range fields {
+		key, value, found := strings.Cut(f, "=")
+		if !found {
+			return Params{}, fmt.Errorf("invalid parameter %q, expected name=value", f)
+		}
+		if key == "seed" {
+			seed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return Params{}, fmt.Errorf("invalid seed %q", value)
+			}
+			p.Seed = uint32(seed)
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return Params{}, fmt.Errorf("invalid value %q for %s", value, key)
+		}
+		field := p.field(key)
+		if field == nil {
+			return Params{}, fmt.Errorf("unknown parameter %q", key)
+		}
+		*field = v
+	}
+	if err := p.check(); err != "" {
+		return Params{}, errors.New(err)
+	}
+	return p, nil
+}
+
+// field returns the parameter with the given name, or nil
+func (p *Params) field(name string) *float64 {
+	return map[string]*float64{
+		"volume":    &p.Volume,
+		"attack":    &p.Attack,
+		"sustain":   &p.Sustain,
+		"punch":     &p.Punch,
+		"decay":     &p.Decay,
+		"freq":      &p.Freq,
+		"min":       &p.Min,
+		"slide":     &p.Slide,
+		"dslide":    &p.DSlide,
+		"vibrato":   &p.Vibrato,
+		"vibspeed":  &p.VibSpeed,
+		"arp":       &p.Arp,
+		"arptime":   &p.ArpTime,
+		"duty":      &p.Duty,
+		"dutysweep": &p.DutySweep,
+		"repeat":    &p.Repeat,
+		"lowpass":   &p.Lowpass,
+		"highpass":  &p.Highpass,
+	}[name]
+}
+
+// check returns what is wrong with the parameters, or ""
+func (p *Params) check() string {
+	switch {
+	case p.Volume < 0 || p.Volume > 1:
+		return "the volume goes from 0 to 1"
+	case p.Duty < 0 || p.Duty > 1:
+		return "the duty goes from 0 to 1"
+	case p.Freq <= 0:
+		return "the frequency must be above 0"
+	case p.Vibrato < 0 || p.Vibrato >= 1:
+		return "the vibrato goes from 0 to below 1"
+	case p.Arp <= 0:
+		return "the arp must be above 0"
+	case p.Attack < 0 || p.Sustain < 0 || p.Decay < 0 || p.Repeat < 0 || p.ArpTime < 0:
+		return "times can not be negative"
+	case p.Attack+p.Sustain+p.Decay <= 0:
+		return "the sound has no length, set attack, sustain or decay"
+	case p.Attack+p.Sustain+p.Decay > MAX_SECONDS:
+		return fmt.Sprintf("the sound is longer than %d seconds", MAX_SECONDS)
+	case p.Lowpass < 0 || p.Highpass < 0 || p.Lowpass > SAMPLE_RATE/2 || p.Highpass > SAMPLE_RATE/2:
+		return fmt.Sprintf("filters go from 0 to %d Hz", SAMPLE_RATE/2)
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+package sfx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+// params are sounds with every wave and most of the parameters
+var params = map[string]string{
+	"square":   "square freq=1200 slide=-3 sustain=0.03 decay=0.08 duty=0.25 dutysweep=2",
+	"saw":      "saw attack=0.02 sustain=0.1 punch=0.8 decay=0.2 vibrato=0.1 vibspeed=12",
+	"triangle": "triangle freq=300 arp=1.5 arptime=0.05 repeat=0.1 sustain=0.3",
+	"sine":     "sine volume=1 punch=1 sustain=0.2 highpass=200",
+	"noise":    "noise freq=600 slide=-1 dslide=0.5 sustain=0.1 punch=0.6 decay=0.5 lowpass=3000 seed=7",
+	"vibrato":  "noise freq=2000 vibrato=0.99 vibspeed=30 sustain=0.5",
+}
+
+func parse(t *testing.T, line string) *Sound {
+	t.Helper()
+	sounds, err := Parse(strings.NewReader("test "+line), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sounds["test"]
+}
+
+func TestLength(t *testing.T) {
+	for name, line := range params {
+		s := parse(t, line)
+		want := int((s.Attack + s.Sustain + s.Decay) * SAMPLE_RATE)
+		if got := len(s.Generate()); got != want {
+			t.Errorf("%s: %d samples, want %d", name, got, want)
+		}
+	}
+}
+
+// TestMin checks that a sound stops when it slides below the minimum frequency
+func TestMin(t *testing.T) {
+	s := parse(t, "square freq=800 slide=-4 min=400 sustain=1")
+	// one octave down at four octaves a second
+	if got, want := len(s.Generate()), SAMPLE_RATE/4; got < want-1 || got > want+1 {
+		t.Errorf("%d samples, want %d", got, want)
+	}
+}
+
+func TestSame(t *testing.T) {
+	for name, line := range params {
+		a, b := parse(t, line).Generate(), parse(t, line).Generate()
+		if !slices.Equal(a, b) {
+			t.Errorf("%s: two sounds with the same parameters differ", name)
+		}
+	}
+	noise := parse(t, params["noise"])
+	other := *noise
+	other.Seed = 8
+	if slices.Equal(noise.Generate(), other.Generate()) {
+		t.Error("the noise is the same with another seed")
+	}
+}
+
+func TestRange(t *testing.T) {
+	for name, line := range params {
+		silent := true
+		for i, v := range parse(t, line).Generate() {
+			if v < -1 || v > 1 {
+				t.Errorf("%s: sample %d is %g", name, i, v)
+				break
+			}
+			silent = silent && v == 0
+		}
+		if silent {
+			t.Errorf("%s: every sample is 0", name)
+		}
+	}
+}
+
+func TestWAV(t *testing.T) {
+	samples := []float32{0, 1, -1, 0.5, 2}
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, samples, 22050); err != nil {
+		t.Fatal(err)
+	}
+	var h struct {
+		Riff          [4]byte
+		Size          uint32
+		Wave, Fmt     [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		Rate          uint32
+		BytesPerSec   uint32
+		BytesPerFrame uint16
+		Bits          uint16
+		Data          [4]byte
+		DataSize      uint32
+	}
+	if err := binary.Read(&buf, binary.LittleEndian, &h); err != nil {
+		t.Fatal(err)
+	}
+	if string(h.Riff[:]) != "RIFF" || string(h.Wave[:]) != "WAVE" || string(h.Fmt[:]) != "fmt " || string(h.Data[:]) != "data" {
+		t.Errorf("the chunk IDs are %q %q %q %q", h.Riff, h.Wave, h.Fmt, h.Data)
+	}
+	if h.Size != 36+10 || h.FmtSize != 16 || h.Format != 1 || h.Channels != 1 || h.Rate != 22050 ||
+		h.BytesPerSec != 44100 || h.BytesPerFrame != 2 || h.Bits != 16 || h.DataSize != 10 {
+		t.Errorf("the header is %+v", h)
+	}
+	data := make([]int16, len(samples))
+	if err := binary.Read(&buf, binary.LittleEndian, data); err != nil {
+		t.Fatal(err)
+	}
+	// samples outside of -1 to 1 are clipped
+	if want := []int16{0, 32767, -32767, 16383, 32767}; !slices.Equal(data, want) {
+		t.Errorf("the data is %v, want %v", data, want)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes after the data", buf.Len())
+	}
+}
+
+func TestParse(t *testing.T) {
+	sounds, err := Parse(strings.NewReader("# a comment\n\nshoot square freq=1200 seed=3 # the shot\n"), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Defaults(Square)
+	want.Freq, want.Seed = 1200, 3
+	if s := sounds["shoot"]; s == nil || s.Params != want || s.Line != 3 || s.Filename != "test.txt" {
+		t.Errorf("shoot is %+v", s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"shoot", 1, "usage: <name> <wave> <parameter>=<value> ..."},
+		{"a sine\n\na sine", 3, "a is already defined on line 1"},
+		{"a organ", 1, `unknown wave "organ", expected one of square, saw, triangle, sine, noise`},
+		{"a sine freq", 1, `invalid parameter "freq", expected name=value`},
+		{"a noise seed=-1", 1, `invalid seed "-1"`},
+		{"a sine freq=high", 1, `invalid value "high" for freq`},
+		{"a sine freq=NaN", 1, `invalid value "NaN" for freq`},
+		{"a sine pitch=2", 1, `unknown parameter "pitch"`},
+		{"# volume\na sine volume=2", 2, "the volume goes from 0 to 1"},
+		{"a square duty=1.5", 1, "the duty goes from 0 to 1"},
+		{"a sine freq=0", 1, "the frequency must be above 0"},
+		{"a noise vibrato=2 vibspeed=10 sustain=0.5", 1, "the vibrato goes from 0 to below 1"},
+		{"b noise arp=-1 arptime=0.01", 1, "the arp must be above 0"},
+		{"a sine decay=-1", 1, "times can not be negative"},
+		{"a sine sustain=0", 1, "the sound has no length, set attack, sustain or decay"},
+		{"a sine sustain=6", 1, "the sound is longer than 5 seconds"},
+		{"a sine lowpass=30000", 1, "filters go from 0 to 22050 Hz"},
+	} {
+		_, err := Parse(strings.NewReader(tt.src), "bad.txt")
+		textfile.Check(t, err, "bad.txt", tt.line, tt.msg)
+	}
+}
+
+// TestLoad loads the sounds that ship with the game
+func TestLoad(t *testing.T) {
+	sounds, err := Load("../data/sounds.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range sounds {
+		if len(s.Generate()) == 0 {
+			t.Errorf("%s has no samples", name)
+		}
+	}
+}
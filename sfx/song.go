@@ -0,0 +1,209 @@
+package sfx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"hi/textfile"
+)
+
+// MAX_SONG_SECONDS is the longest a song can be
+const MAX_SONG_SECONDS = 60
+
+// Song is a tune played by instruments that are synthesised like the sound
+// effects. A song file has the tempo, the instruments and the notes that
+// each of them plays, one step after another:
+//
+//	tempo 480     # steps per minute
+//	instrument lead square volume=0.2 sustain=0.08 decay=0.1 duty=0.25
+//	instrument bass triangle volume=0.4 sustain=0.15
+//	lead  e5 . g5 . a5 . . .
+//	bass  e2 . . . e2 . . .
+//
+// A note is a letter from a to g, an s for sharp, and the octave from 0 to 8,
+// like c4 or fs2. A dot is a rest. The notes of an instrument go on from one
+// line to the next. The freq of an instrument is replaced by the note it
+// plays.
+//
+// Songs loop, so the end of the last notes plays over the start.
+type Song struct {
+	Filename    string
+	Tempo       float64
+	Instruments []*Instrument
+}
+
+// Instrument is an instrument of a song, with the notes it plays
+type Instrument struct {
+	Name  string
+	Line  int
+	Notes []int // MIDI note numbers, 0 for a rest
+	Params
+}
+
+// semitones are the notes from c, without sharps
+var semitones = map[byte]int{'c': 0, 'd': 2, 'e': 4, 'f': 5, 'g': 7, 'a': 9, 'b': 11}
+
+// LoadSong reads and parses a song file
+func LoadSong(filename string) (*Song, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSong(f, filename)
+}
+
+// ParseSong reads a song from r. The filename is only used for error
+// messages.
+func ParseSong(r io.Reader, filename string) (*Song, error) {
+	s := &Song{Filename: filename}
+	errorf := func(line int, format string, args ...any) error {
+		return textfile.Errorf(filename, line, format, args...)
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "tempo":
+			if len(fields) != 2 {
+				return nil, errorf(line, "usage: tempo <steps per minute>")
+			}
+			tempo, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || !(tempo > 0) || tempo > 60*SAMPLE_RATE {
+				return nil, errorf(line, "invalid tempo %q, expected a number above 0 and up to %d", fields[1], 60*SAMPLE_RATE)
+			}
+			s.Tempo = tempo
+		case "instrument":
+			if len(fields) < 3 {
+				return nil, errorf(line, "usage: instrument <name> <wave> <parameter>=<value> ...")
+			}
+			if i := s.instrument(fields[1]); i != nil {
+				return nil, errorf(line, "%s is already defined on line %d", i.Name, i.Line)
+			}
+			params, err := parseParams(fields[2], fields[3:])
+			if err != nil {
+				return nil, errorf(line, "%v", err)
+			}
+			s.Instruments = append(s.Instruments, &Instrument{Name: fields[1], Line: line, Params: params})
+		default:
+			i := s.instrument(fields[0])
+			if i == nil {
+				return nil, errorf(line, "unknown instrument %q", fields[0])
+			}
+			for _, f := range fields[1:] {
+				note, ok := parseNote(f)
+				if !ok {
+					return nil, errorf(line, "invalid note %q, expected a rest or a note like c4 or fs2", f)
+				}
+				i.Notes = append(i.Notes, note)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	switch {
+	case s.Tempo == 0:
+		return nil, fmt.Errorf("%s: the song has no tempo", filename)
+	case s.Steps() == 0:
+		return nil, fmt.Errorf("%s: the song has no notes", filename)
+	case s.Length() > MAX_SONG_SECONDS:
+		return nil, fmt.Errorf("%s: the song is longer than %d seconds", filename, MAX_SONG_SECONDS)
+	}
+	return s, nil
+}
+
+// instrument returns the instrument with the given name, or nil
+func (s *Song) instrument(name string) *Instrument {
+	for _, i := range s.Instruments {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// parseNote returns the MIDI note number of a note, or 0 for a rest
+func parseNote(s string) (int, bool) {
+	if s == "." {
+		return 0, true
+	}
+	if len(s) < 2 || len(s) > 3 {
+		return 0, false
+	}
+	note, ok := semitones[s[0]]
+	if !ok {
+		return 0, false
+	}
+	if len(s) == 3 {
+		if s[1] != 's' {
+			return 0, false
+		}
+		note++
+	}
+	octave := s[len(s)-1]
+	if octave < '0' || octave > '8' {
+		return 0, false
+	}
+	// c4 is the middle c, 60
+	return 12*(int(octave-'0')+1) + note, true
+}
+
+// Steps returns the number of steps in one loop of the song, which is as
+// long as the instrument with the most notes
+func (s *Song) Steps() int {
+	steps := 0
+	for _, i := range s.Instruments {
+		steps = max(steps, len(i.Notes))
+	}
+	return steps
+}
+
+// stepSamples returns the length of a step in samples
+func (s *Song) stepSamples() int {
+	return int(SAMPLE_RATE * 60 / s.Tempo)
+}
+
+// Length returns the length of one loop of the song in seconds
+func (s *Song) Length() float64 {
+	return float64(s.Steps()*s.stepSamples()) / SAMPLE_RATE
+}
+
+// Generate returns the samples of one loop of the song, from -1 to 1, at
+// SAMPLE_RATE
+func (s *Song) Generate() []float32 {
+	step := s.stepSamples()
+	mixed := make([]float64, s.Steps()*step)
+	for _, i := range s.Instruments {
+		notes := make(map[int][]float32)
+		for n, note := range i.Notes {
+			if note == 0 {
+				continue
+			}
+			samples, ok := notes[note]
+			if !ok {
+				p := i.Params
+				p.Freq = 440 * math.Exp2(float64(note-69)/12)
+				samples = p.Generate()
+				notes[note] = samples
+			}
+			for j, v := range samples {
+				mixed[(n*step+j)%len(mixed)] += float64(v)
+			}
+		}
+	}
+	samples := make([]float32, len(mixed))
+	for i, v := range mixed {
+		samples[i] = float32(min(max(v, -1), 1))
+	}
+	return samples
+}
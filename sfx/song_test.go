@@ -0,0 +1,120 @@
+package sfx
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/textfile"
+)
+
+func parseSong(t *testing.T, src string) *Song {
+	t.Helper()
+	s, err := ParseSong(strings.NewReader(src), "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParseSong(t *testing.T) {
+	s := parseSong(t, `# a comment
+tempo 600
+instrument lead square volume=0.2
+instrument bass triangle
+lead a4 . cs5
+bass c4
+lead . c0 b8 # the last notes
+`)
+	if s.Tempo != 600 || len(s.Instruments) != 2 {
+		t.Fatalf("the song is %+v", s)
+	}
+	lead, bass := s.Instruments[0], s.Instruments[1]
+	if lead.Name != "lead" || lead.Line != 3 || lead.Wave != Square || lead.Volume != 0.2 {
+		t.Errorf("lead is %+v", lead)
+	}
+	if want := []int{69, 0, 73, 0, 12, 119}; !slices.Equal(lead.Notes, want) {
+		t.Errorf("lead plays %v, want %v", lead.Notes, want)
+	}
+	if !slices.Equal(bass.Notes, []int{60}) {
+		t.Errorf("bass plays %v", bass.Notes)
+	}
+	// ten steps a second, as long as the lead
+	if s.Steps() != 6 || s.Length() != 0.6 {
+		t.Errorf("the song has %d steps and is %gs long", s.Steps(), s.Length())
+	}
+}
+
+func TestParseSongErrors(t *testing.T) {
+	const head = "tempo 600\ninstrument a sine\n"
+	for _, tt := range []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"tempo", 1, "usage: tempo <steps per minute>"},
+		{"tempo 0", 1, `invalid tempo "0", expected a number above 0 and up to 2646000`},
+		{"tempo fast", 1, `invalid tempo "fast", expected a number above 0 and up to 2646000`},
+		{"instrument a", 1, "usage: instrument <name> <wave> <parameter>=<value> ..."},
+		{head + "\ninstrument a saw", 4, "a is already defined on line 2"},
+		{"instrument a organ", 1, `unknown wave "organ", expected one of square, saw, triangle, sine, noise`},
+		{"instrument a sine volume=2", 1, "the volume goes from 0 to 1"},
+		{head + "b c4", 3, `unknown instrument "b"`},
+		{head + "a c4 h4", 3, `invalid note "h4", expected a rest or a note like c4 or fs2`},
+		{head + "a c9", 3, `invalid note "c9", expected a rest or a note like c4 or fs2`},
+		{head + "a cb4", 3, `invalid note "cb4", expected a rest or a note like c4 or fs2`},
+	} {
+		_, err := ParseSong(strings.NewReader(tt.src), "bad.txt")
+		textfile.Check(t, err, "bad.txt", tt.line, tt.msg)
+	}
+	for _, tt := range []struct{ src, msg string }{
+		{"instrument a sine\na c4", "bad.txt: the song has no tempo"},
+		{"tempo 600\ninstrument a sine", "bad.txt: the song has no notes"},
+		{"tempo 60\ninstrument a sine\na c4" + strings.Repeat(" .", 60), "bad.txt: the song is longer than 60 seconds"},
+	} {
+		if _, err := ParseSong(strings.NewReader(tt.src), "bad.txt"); err == nil || err.Error() != tt.msg {
+			t.Errorf("got %v, want %s", err, tt.msg)
+		}
+	}
+}
+
+// TestLoop checks that a note that is longer than the rest of the song
+// plays on from the start, so that the song loops without a gap
+func TestLoop(t *testing.T) {
+	s := parseSong(t, "tempo 600\ninstrument a sine volume=1 sustain=0.15\na . c4\n")
+	samples := s.Generate()
+	if len(samples) != 2*SAMPLE_RATE/10 {
+		t.Fatalf("%d samples, want %d", len(samples), 2*SAMPLE_RATE/10)
+	}
+	step := SAMPLE_RATE / 10
+	note := Defaults(Sine)
+	note.Volume, note.Sustain, note.Freq = 1, 0.15, 440*math.Exp2(-9.0/12)
+	want := note.Generate()
+	if !slices.Equal(samples[step:], want[:step]) {
+		t.Error("the note does not start on the second step")
+	}
+	if !slices.Equal(samples[:len(want)-step], want[step:]) {
+		t.Error("the end of the note does not play over the start")
+	}
+}
+
+// TestLoadSong loads the music that ships with the game
+func TestLoadSong(t *testing.T) {
+	s, err := LoadSong("../data/music.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range s.Instruments {
+		if len(i.Notes) != s.Steps() {
+			t.Errorf("%s plays %d steps of %d", i.Name, len(i.Notes), s.Steps())
+		}
+	}
+	silent := true
+	for _, v := range s.Generate() {
+		silent = silent && v == 0
+	}
+	if silent {
+		t.Error("the music is silent")
+	}
+}
@@ -0,0 +1,51 @@
+package sfx
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// WriteWAV writes samples from -1 to 1 as a mono 16 bit WAV file
+func WriteWAV(w io.Writer, samples []float32, rate int) error {
+	const channels, bits = 1, 16
+	data := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(min(max(s, -1), 1)*32767)))
+	}
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(36 + len(data)),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16), // size of the format chunk
+		uint16(1),  // PCM
+		uint16(channels),
+		uint32(rate),
+		uint32(rate * channels * bits / 8), // bytes per second
+		uint16(channels * bits / 8),        // bytes per sample
+		uint16(bits),
+		[4]byte{'d', 'a', 't', 'a'},
+		uint32(len(data)),
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// SaveWAV generates the sound and writes it to a WAV file
+func (p *Params) SaveWAV(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteWAV(f, p.Generate(), SAMPLE_RATE); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
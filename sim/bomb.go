@@ -0,0 +1,73 @@
+package sim
+
+const (
+	BOMBS        = 2   // bombs at the start of the game
+	BOMB_RADIUS  = 120 // bullets within this distance of the ship are cleared
+	BOMB_TICKS   = 40  // how long the blast takes to reach its full radius
+	BOMB_DAMAGE  = 10  // damage to every enemy on the screen, and to the boss
+	BOMB_INVULN  = 90  // ticks of invulnerability
+	BOMB_FLASHES = 20  // ticks of the full-screen flash
+)
+
+// Blast is an expanding bomb blast
+type Blast struct {
+	X     float64
+	Y     float64
+	Age   uint64
+	Owner int // the player that set off the bomb
+}
+
+// Radius returns how far the blast has reached
+func (b *Blast) Radius() float64 {
+	return BOMB_RADIUS * float64(min(b.Age, BOMB_TICKS)) / BOMB_TICKS
+}
+
+// bomb sets off a bomb of player i, if there are any left, and if no other
+// blast is going on. Every enemy on the screen is damaged right away, while
+// enemy bullets are cleared as the blast expands.
+func (w *World) bomb(i int) {
+	p := &w.Players[i]
+	if p.Bombs <= 0 || w.Blast != nil {
+		return
+	}
+	p.Bombs--
+	w.Blast = &Blast{X: p.Ship.X + SHIP_W/2, Y: p.Ship.Y + SHIP_H/2, Owner: i}
+	p.Invuln = max(p.Invuln, BOMB_INVULN)
+	w.publish(BombUsed{i})
+	for j := range w.Enemies {
+		e := &w.Enemies[j]
+		if e.X > -ENEMY_W && e.X < W && e.Y > -ENEMY_H && e.Y < H {
+			e.HP -= BOMB_DAMAGE
+			e.LastHit = i
+		}
+	}
+	if b := w.Boss; b != nil && b.Y >= BOSS_Y {
+		b.HP -= BOMB_DAMAGE
+		b.Hit = BOMB_FLASHES
+		b.LastHit = i
+	}
+}
+
+// updateBlast expands the blast and clears the enemy bullets inside of it
+func (w *World) updateBlast() {
+	b := w.Blast
+	if b == nil {
+		return
+	}
+	b.Age++
+	r := b.Radius()
+	alive := w.Bullets[:0]
+	for _, bullet := range w.Bullets {
+		dx := bullet.X + ENEMY_BULLET_W/2 - b.X
+		dy := bullet.Y + ENEMY_BULLET_H/2 - b.Y
+		if bullet.Hostile && dx*dx+dy*dy < r*r {
+			continue
+		}
+		alive = append(alive, bullet)
+	}
+	clear(w.Bullets[len(alive):])
+	w.Bullets = alive
+	if b.Age >= BOMB_TICKS {
+		w.Blast = nil
+	}
+}
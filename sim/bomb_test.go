@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestBomb checks that a bomb damages the enemies on the screen, clears the
+// enemy bullets that the blast reaches and makes the ship invulnerable
+func TestBomb(t *testing.T) {
+	w := NewWorld()
+	p := &w.Players[0]
+	p.Invuln = 0
+	cx, cy := p.Ship.X+SHIP_W/2, p.Ship.Y+SHIP_H/2
+	near := Bullet{X: cx, Y: cy - 60, Life: 1000, Hostile: true}
+	far := Bullet{X: W - 20, Y: 20, Life: 1000, Hostile: true}
+	shot := Bullet{X: cx, Y: cy - 100, Life: 1000}
+	w.Bullets = append(w.Bullets, near, far, shot)
+	tough := &Kind{Name: "tough", HP: 3 * BOMB_DAMAGE}
+	w.spawnEnemy(tough, 40, 40, nil)
+	w.spawnEnemy(tough, 40, -ENEMY_H-4, nil)
+
+	w.Step([]Input{Bomb})
+	if !slices.Equal(w.Events, []Event{BombUsed{0}}) {
+		t.Errorf("the bomb published %v", w.Events)
+	}
+	if p.Bombs != BOMBS-1 || p.Invuln < BOMB_INVULN-1 || w.Blast == nil {
+		t.Fatalf("after the bomb, there are %d bombs, %d ticks of invulnerability and the blast is %v", p.Bombs, p.Invuln, w.Blast)
+	}
+	if hp := w.Enemies[0].HP; hp != 2*BOMB_DAMAGE {
+		t.Errorf("the enemy on the screen has %d HP, want %d", hp, 2*BOMB_DAMAGE)
+	}
+	if hp := w.Enemies[1].HP; hp != 3*BOMB_DAMAGE {
+		t.Errorf("the enemy above the screen has %d HP, want %d", hp, 3*BOMB_DAMAGE)
+	}
+
+	// holding the button does not set off another bomb
+	for w.Blast != nil {
+		w.Step([]Input{Bomb})
+	}
+	if p.Bombs != BOMBS-1 {
+		t.Errorf("holding the bomb button used %d bombs", BOMBS-p.Bombs)
+	}
+	var hostile, friendly int
+	for _, b := range w.Bullets {
+		switch {
+		case b.Hostile && b.X == near.X && b.Y == near.Y:
+			t.Error("the bullet next to the ship was not cleared")
+		case b.Hostile:
+			hostile++
+		default:
+			friendly++
+		}
+	}
+	if hostile != 1 || friendly != 1 {
+		t.Errorf("%d enemy bullets and %d of the player are left, want 1 and 1", hostile, friendly)
+	}
+}
+
+// TestBombInvulnerable checks that the ship is not hurt by a bullet right
+// after a bomb, and that there are no bombs to set off once they are used up
+func TestBombInvulnerable(t *testing.T) {
+	w := NewWorld()
+	p := &w.Players[0]
+	p.Invuln, p.Bombs = 0, 1
+	w.Step([]Input{Bomb})
+	w.Step(nil)
+	w.Bullets = append(w.Bullets, Bullet{X: p.Ship.X + SHIP_W/2, Y: p.Ship.Y + SHIP_H/2, Life: 10, Hostile: true})
+	w.Step(nil)
+	if p.Lives != LIVES || slices.ContainsFunc(w.Events, func(e Event) bool { _, ok := e.(PlayerHit); return ok }) {
+		t.Errorf("the ship was hit after the bomb, and has %d lives", p.Lives)
+	}
+	for w.Blast != nil {
+		w.Step(nil)
+	}
+	w.Step([]Input{Bomb})
+	if w.Blast != nil || p.Bombs != 0 {
+		t.Errorf("a bomb went off without bombs, %d are left", p.Bombs)
+	}
+}
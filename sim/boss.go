@@ -0,0 +1,243 @@
+package sim
+
+import (
+	"math"
+
+	"hi/pattern"
+)
+
+// Attack runs a bullet pattern from a part of the boss, over and over
+type Attack struct {
+	Part    string
+	Pattern string
+}
+
+// Phase is a stage of a boss fight, which lasts until its HP is used up
+type Phase struct {
+	HP      int
+	Sway    float64 // how far the boss moves from side to side
+	Attacks []Attack
+}
+
+// PartKind is a hitbox of a boss, relative to the top left corner of the boss.
+// Parts with HP are destroyed separately, armoured parts absorb bullets without
+// taking damage, and other parts pass the damage on to the current phase.
+type PartKind struct {
+	Name   string
+	X      float64
+	Y      float64
+	W      float64
+	H      float64
+	HP     int
+	Armour bool
+}
+
+// BossKind describes a boss
+type BossKind struct {
+	Name   string
+	Score  int
+	W      float64
+	H      float64
+	Parts  []PartKind
+	Phases []Phase
+}
+
+// Bosses are the bosses that level files can trigger
+var Bosses = map[string]*BossKind{
+	"warden": {
+		Name:  "warden",
+		Score: 5000,
+		W:     64,
+		H:     32,
+		Parts: []PartKind{
+			{Name: "hull", X: 0, Y: 0, W: 64, H: 22, Armour: true},
+			{Name: "core", X: 26, Y: 19, W: 12, H: 12},
+			{Name: "left", X: 8, Y: 16, W: 12, H: 12, HP: 30},
+			{Name: "right", X: 44, Y: 16, W: 12, H: 12, HP: 30},
+		},
+		Phases: []Phase{
+			{HP: 60, Sway: 40, Attacks: []Attack{
+				{Part: "left", Pattern: "aimed3"},
+				{Part: "right", Pattern: "aimed3"},
+				{Part: "core", Pattern: "ring12"},
+			}},
+			{HP: 80, Sway: 80, Attacks: []Attack{
+				{Part: "core", Pattern: "spiral3"},
+				{Part: "left", Pattern: "aimed5"},
+				{Part: "right", Pattern: "aimed5"},
+			}},
+			{HP: 100, Sway: 100, Attacks: []Attack{
+				{Part: "core", Pattern: "spiral4"},
+				{Part: "core", Pattern: "flower"},
+				{Part: "core", Pattern: "snipe"},
+			}},
+		},
+	},
+}
+
+// Part is a hitbox of a boss that is alive
+type Part struct {
+	Kind  *PartKind
+	HP    int
+	MaxHP int // what the part started with, after the difficulty
+}
+
+type Boss struct {
+	Kind  *BossKind
+	X     float64
+	Y     float64
+	Parts []Part
+	Phase int
+	HP    int    // health left in the current phase
+	Age   uint64 // ticks since the current phase started
+	Hit   uint64 // ticks left of the hit flash
+
+	Health float64 // multiplies the health of the phases and parts, see Difficulty
+
+	LastHit int // the player that hit it last, and gets the points
+
+	// Attacks has a runner for every attack of the current phase
+	Attacks []*pattern.Runner
+}
+
+// BOSS_Y is where the boss stops after entering the screen from the top
+const BOSS_Y = 16
+
+func (w *World) spawnBoss(kind *BossKind) {
+	b := &Boss{
+		Kind:    kind,
+		X:       (W - kind.W) / 2,
+		Y:       -kind.H,
+		LastHit: -1,
+		Health:  w.Difficulty.Health,
+	}
+	for i := range kind.Parts {
+		hp := health(kind.Parts[i].HP, b.Health)
+		b.Parts = append(b.Parts, Part{&kind.Parts[i], hp, hp})
+	}
+	b.startPhase()
+	w.Boss = b
+	w.publish(BossSpawned{kind.Name})
+}
+
+// startPhase starts the attacks of the current phase
+func (b *Boss) startPhase() {
+	b.HP = health(b.Kind.Phases[b.Phase].HP, b.Health)
+	b.Age = 0
+	b.Attacks = b.Attacks[:0]
+	for _, a := range b.Kind.Phases[b.Phase].Attacks {
+		b.Attacks = append(b.Attacks, pattern.NewRunner(Patterns[a.Pattern], math.Pi/2, 1))
+	}
+}
+
+// part returns the part with the given name, if it has not been destroyed
+func (b *Boss) part(name string) *Part {
+	for i := range b.Parts {
+		p := &b.Parts[i]
+		if p.Kind.Name == name && (p.Kind.HP == 0 || p.HP > 0) {
+			return p
+		}
+	}
+	return nil
+}
+
+// MaxHP returns the health of each phase, for drawing health bars
+func (b *Boss) MaxHP() []int {
+	hp := make([]int, len(b.Kind.Phases))
+	for i, phase := range b.Kind.Phases {
+		hp[i] = health(phase.HP, b.Health)
+	}
+	return hp
+}
+
+func (w *World) updateBoss() {
+	b := w.Boss
+	if b == nil {
+		return
+	}
+	if b.Hit > 0 {
+		b.Hit--
+	}
+	if b.HP <= 0 {
+		w.nextPhase()
+		if w.Boss == nil {
+			return
+		}
+	}
+	b.Age++
+	phase := &b.Kind.Phases[b.Phase]
+	if b.Y < BOSS_Y {
+		b.Y += 0.5
+		return
+	}
+	// move towards a point that swings from side to side, so that the
+	// boss does not jump when the sway changes between phases
+	target := (W-b.Kind.W)/2 + phase.Sway*math.Sin(float64(w.Tick)/90)
+	b.X += math.Max(-1, math.Min(1, target-b.X))
+	for i, a := range phase.Attacks {
+		p := b.part(a.Part)
+		if p == nil {
+			continue
+		}
+		r := b.Attacks[i]
+		if r.Done() {
+			r.Restart(Patterns[a.Pattern])
+		}
+		r.Step(&emitter{w, b.X + p.Kind.X + p.Kind.W/2, b.Y + p.Kind.Y + p.Kind.H/2})
+	}
+}
+
+// hitBoss checks if the bullet hits a part of the boss, and damages it if so
+func (w *World) hitBoss(bullet *Bullet) bool {
+	b := w.Boss
+	if b == nil {
+		return false
+	}
+	// parts that are listed last are on top
+	for i := len(b.Parts) - 1; i >= 0; i-- {
+		p := &b.Parts[i]
+		if p.Kind.HP > 0 && p.HP <= 0 {
+			continue
+		}
+		if !overlaps(bullet.X, bullet.Y, BULLET_W, BULLET_H, b.X+p.Kind.X, b.Y+p.Kind.Y, p.Kind.W, p.Kind.H) {
+			continue
+		}
+		switch {
+		case p.Kind.HP > 0:
+			p.HP--
+		case !p.Kind.Armour:
+			b.HP--
+			b.Hit = 4
+			b.LastHit = bullet.Owner
+		}
+		return true
+	}
+	return false
+}
+
+// nextPhase moves the boss on to its next phase, or removes it after the last one
+func (w *World) nextPhase() {
+	b := w.Boss
+	// bullets from the previous phase are cleared, to give the player a break
+	w.clearHostileBullets()
+	if b.Phase+1 >= len(b.Kind.Phases) {
+		w.award(b.LastHit, b.Kind.Score)
+		w.publish(EnemyKilled{Player: b.LastHit, Kind: b.Kind.Name, Boss: true, X: b.X + b.Kind.W/2, Y: b.Y + b.Kind.H/2})
+		w.drop(b.Kind.Name, b.X+b.Kind.W/2, b.Y+b.Kind.H/2)
+		w.Boss = nil
+		return
+	}
+	b.Phase++
+	b.startPhase()
+}
+
+func (w *World) clearHostileBullets() {
+	alive := w.Bullets[:0]
+	for _, b := range w.Bullets {
+		if !b.Hostile {
+			alive = append(alive, b)
+		}
+	}
+	w.Bullets = alive
+	w.fired = w.fired[:0]
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// shootPart hits the top of the named part of the boss with a bullet of
+// player 0, and returns true if the bullet was stopped
+func shootPart(w *World, name string) bool {
+	b := w.Boss
+	for _, p := range b.Parts {
+		if p.Kind.Name == name {
+			x := b.X + p.Kind.X + p.Kind.W/2 - BULLET_W/2
+			y := b.Y + p.Kind.Y
+			return w.hitBoss(&Bullet{X: x, Y: y, Owner: 0})
+		}
+	}
+	panic("the boss has no part " + name)
+}
+
+// TestBoss fights the warden: armour takes no damage, a part with its own
+// health is destroyed on its own, and the core takes the boss through its
+// phases until it is killed
+func TestBoss(t *testing.T) {
+	if err := LoadData(".."); err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorld()
+	w.spawnBoss(Bosses["warden"])
+	if !slices.Equal(w.Events, []Event{BossSpawned{"warden"}}) {
+		t.Errorf("spawning the boss published %v", w.Events)
+	}
+	for w.Boss.Y < BOSS_Y {
+		w.Step(nil)
+	}
+	b := w.Boss
+	phases := b.MaxHP()
+	if !slices.Equal(phases, []int{60, 80, 100}) || b.HP != 60 {
+		t.Fatalf("the phases have %v HP, and the first one %d", phases, b.HP)
+	}
+
+	if !shootPart(w, "hull") || b.HP != 60 {
+		t.Errorf("a shot at the armour went through, or did %d damage", 60-b.HP)
+	}
+	for range 30 {
+		shootPart(w, "left")
+	}
+	if b.part("left") != nil || b.HP != 60 {
+		t.Errorf("the left gun is not destroyed after 30 hits, or the boss has %d HP", b.HP)
+	}
+	if !shootPart(w, "core") || b.HP != 59 || b.LastHit != 0 {
+		t.Errorf("a shot at the core left the boss at %d HP, hit last by %d", b.HP, b.LastHit)
+	}
+
+	for phase := 1; phase < len(phases); phase++ {
+		for b.HP > 0 {
+			shootPart(w, "core")
+		}
+		w.Bullets = append(w.Bullets, Bullet{X: 10, Y: 200, Life: 100, Hostile: true})
+		w.Step(nil)
+		if b.Phase != phase || b.HP != phases[phase] || len(b.Attacks) != 3 {
+			t.Fatalf("the boss is in phase %d with %d HP and %d attacks, want phase %d", b.Phase, b.HP, len(b.Attacks), phase)
+		}
+		if slices.ContainsFunc(w.Bullets, func(b Bullet) bool { return b.Hostile && b.X == 10 && b.Y == 200 }) {
+			t.Errorf("the bullets of phase %d were not cleared", phase)
+		}
+	}
+
+	for b.HP > 0 {
+		shootPart(w, "core")
+	}
+	score := w.Players[0].Score
+	w.Step(nil)
+	if w.Boss != nil {
+		t.Fatal("the boss is still there after its last phase")
+	}
+	if w.Players[0].Score != score+b.Kind.Score {
+		t.Errorf("the player got %d points for the boss, want %d", w.Players[0].Score-score, b.Kind.Score)
+	}
+	if !slices.ContainsFunc(w.Events, func(e Event) bool {
+		k, ok := e.(EnemyKilled)
+		return ok && k.Boss && k.Kind == "warden" && k.Player == 0
+	}) {
+		t.Errorf("killing the boss published %v", w.Events)
+	}
+}
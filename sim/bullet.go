@@ -0,0 +1,74 @@
+package sim
+
+import (
+	"math"
+
+	"hi/pattern"
+)
+
+type Bullet struct {
+	X       float64
+	Y       float64
+	VX      float64
+	VY      float64
+	Life    uint64
+	Homing  float64 // maximum turn per tick towards the nearest enemy, in radians
+	Hostile bool    // fired by an enemy, hurts the ship instead of enemies
+	Owner   int     // the player that fired the bullet, if it is not hostile
+
+	// Action controls the direction and speed of the bullet, if it is set
+	Action *pattern.Runner
+}
+
+// moveBullet advances the bullet by one tick
+func (w *World) moveBullet(b *Bullet) {
+	if b.Action != nil {
+		b.Action.Step(&emitter{w, b.X + ENEMY_BULLET_W/2, b.Y + ENEMY_BULLET_H/2})
+		b.VX, b.VY = b.Action.Speed*math.Cos(b.Action.Dir), b.Action.Speed*math.Sin(b.Action.Dir)
+	}
+	if b.Homing > 0 {
+		if e := w.nearestEnemy(b.X, b.Y); e != nil {
+			speed := math.Hypot(b.VX, b.VY)
+			heading := math.Atan2(b.VY, b.VX)
+			want := math.Atan2(e.Y+ENEMY_H/2-b.Y, e.X+ENEMY_W/2-b.X)
+			// the shortest turn, in the range -Pi to Pi
+			turn := math.Remainder(want-heading, 2*math.Pi)
+			turn = math.Max(-b.Homing, math.Min(b.Homing, turn))
+			heading += turn
+			b.VX, b.VY = speed*math.Cos(heading), speed*math.Sin(heading)
+		}
+	}
+	speed := 1.0
+	if b.Hostile {
+		speed = w.bulletSpeed()
+	}
+	b.X += b.VX * speed
+	b.Y += b.VY * speed
+}
+
+// hit checks if the bullet hits something it can damage
+func (w *World) hit(b *Bullet) bool {
+	if b.Hostile {
+		for i := range w.Players {
+			if w.Players[i].hitbox(b.X, b.Y, ENEMY_BULLET_W, ENEMY_BULLET_H) {
+				w.hurt(i)
+				return true
+			}
+		}
+		return false
+	}
+	if w.hitEnemy(b) || w.hitBoss(b) {
+		w.Players[b.Owner].Stats.Hits++
+		w.publish(BulletHit{b.Owner, b.X, b.Y})
+		return true
+	}
+	return false
+}
+
+// gone returns true if the bullet has vanished or left the screen
+func (b *Bullet) gone() bool {
+	if b.Action != nil && b.Action.Vanished {
+		return true
+	}
+	return b.X < -MARGIN || b.X > W+MARGIN || b.Y < -MARGIN || b.Y > H+MARGIN
+}
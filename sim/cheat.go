@@ -0,0 +1,19 @@
+package sim
+
+import (
+	"fmt"
+)
+
+// Spawn adds an enemy or a boss of the named kind, for testing. Enemies
+// appear at x, y and fall straight down, bosses enter from the top.
+func (w *World) Spawn(kind string, x, y float64) error {
+	if k := Kinds[kind]; k != nil {
+		w.spawnEnemy(k, x, y, nil)
+		return nil
+	}
+	if k := Bosses[kind]; k != nil {
+		w.spawnBoss(k)
+		return nil
+	}
+	return fmt.Errorf("unknown enemy %q", kind)
+}
@@ -0,0 +1,39 @@
+package sim
+
+import (
+	"path/filepath"
+
+	"hi/drops"
+	"hi/level"
+	"hi/pattern"
+)
+
+// LoadData loads the bullet patterns and drop tables of the game from dir,
+// the top of the repository, and uses them
+func LoadData(dir string) error {
+	patterns, err := pattern.LoadDir(filepath.Join(dir, "patterns"))
+	if err == nil {
+		err = UsePatterns(patterns)
+	}
+	if err != nil {
+		return err
+	}
+	tables, err := drops.Load(filepath.Join(dir, "data", "drops.txt"))
+	if err == nil {
+		err = UseDrops(tables)
+	}
+	return err
+}
+
+// LoadLevel returns a world that runs the level in filename, with the
+// given seed and difficulty. The data has to be loaded first.
+func LoadLevel(filename string, seed uint64, difficulty Difficulty) (*World, error) {
+	lvl, err := level.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	w := NewWorld()
+	w.SetSeed(seed)
+	w.SetDifficulty(difficulty)
+	return w, w.Load(lvl)
+}
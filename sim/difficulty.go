@@ -0,0 +1,106 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// Difficulty scales how tough the enemies are. It is set before the first
+// tick, and replays and online games store it with the seed.
+type Difficulty struct {
+	Name        string
+	Health      float64 // multiplies the health of enemies and bosses
+	BulletSpeed float64 // multiplies the speed of enemy bullets
+	Density     float64 // enemy bullets per bullet in a pattern, extra ones fan out
+	Adaptive    bool    // the intensity follows how well the players are doing
+}
+
+// Difficulties are the presets, from easiest to hardest
+var Difficulties = []Difficulty{
+	{Name: "easy", Health: 0.75, BulletSpeed: 0.8, Density: 0.6},
+	{Name: "normal", Health: 1, BulletSpeed: 1, Density: 1},
+	{Name: "hard", Health: 1.5, BulletSpeed: 1.2, Density: 1.5},
+}
+
+// DefaultDifficulty is what NewWorld uses
+var DefaultDifficulty = Difficulties[1]
+
+// FindDifficulty returns the preset with the given name
+func FindDifficulty(name string) (Difficulty, error) {
+	for _, d := range Difficulties {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return Difficulty{}, fmt.Errorf("unknown difficulty %q", name)
+}
+
+const (
+	// the intensity of the adaptive mode stays between these
+	MIN_INTENSITY = 0.6
+	MAX_INTENSITY = 1.4
+
+	ADAPT_SHIELD = 0.04  // intensity taken off when a shield is hit
+	ADAPT_LIFE   = 0.1   // when a life is lost
+	ADAPT_DEATH  = 0.1   // and more when it was the last one
+	ADAPT_CALM   = 600   // ticks without being hit before the intensity goes up
+	ADAPT_RISE   = 0.001 // intensity added every tick after that
+
+	// DENSITY_SPREAD is the angle between the bullets that density adds, in radians
+	DENSITY_SPREAD = 0.08
+)
+
+// SetDifficulty sets the difficulty, and starts the intensity at 1
+func (w *World) SetDifficulty(d Difficulty) {
+	w.Difficulty, w.Intensity = d, 1
+}
+
+// bulletSpeed returns what the speed of enemy bullets is multiplied by.
+// The intensity counts for half, so that bullets do not get too fast to dodge.
+func (w *World) bulletSpeed() float64 {
+	return w.Difficulty.BulletSpeed * (1 + (w.Intensity-1)/2)
+}
+
+// bullets returns how many bullets to fire for each bullet of a pattern.
+// The fractions add up over time, so that a density of 1.5 fires two
+// bullets every other time.
+func (w *World) bullets() int {
+	w.density += w.Difficulty.Density * w.Intensity
+	n := math.Floor(w.density)
+	w.density -= n
+	return int(n)
+}
+
+// health scales the health of an enemy, boss phase or part. Nothing goes
+// down to 0, and parts without health stay that way.
+func health(hp int, scale float64) int {
+	if hp == 0 {
+		return 0
+	}
+	return max(1, int(math.Round(float64(hp)*scale)))
+}
+
+// adapt lowers the intensity when player i was hit
+func (w *World) adapt(i int) {
+	if !w.Difficulty.Adaptive {
+		return
+	}
+	p := &w.Players[i]
+	w.lastHit = w.Tick
+	switch {
+	case p.ShieldTime > 0:
+		w.Intensity -= ADAPT_SHIELD
+	case p.Lives <= 1:
+		w.Intensity -= ADAPT_LIFE + ADAPT_DEATH
+	default:
+		w.Intensity -= ADAPT_LIFE
+	}
+	w.Intensity = max(w.Intensity, MIN_INTENSITY)
+}
+
+// calm raises the intensity slowly while nobody is being hit
+func (w *World) calm() {
+	if w.Difficulty.Adaptive && w.Tick-w.lastHit > ADAPT_CALM {
+		w.Intensity = min(w.Intensity+ADAPT_RISE, MAX_INTENSITY)
+	}
+}
@@ -0,0 +1,142 @@
+package sim
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	for _, tt := range []struct {
+		hp    int
+		scale float64
+		want  int
+	}{
+		{4, 0.75, 3},
+		{2, 0.75, 2},
+		{1, 0.75, 1},
+		{1, 0.1, 1},
+		{0, 1.5, 0},
+		{30, 1.5, 45},
+		{3, 1.5, 5},
+	} {
+		if got := health(tt.hp, tt.scale); got != tt.want {
+			t.Errorf("health(%d, %g) = %d, want %d", tt.hp, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestFindDifficulty(t *testing.T) {
+	for _, d := range Difficulties {
+		if found, err := FindDifficulty(d.Name); err != nil || found != d {
+			t.Errorf("%s: found %+v, %v", d.Name, found, err)
+		}
+	}
+	if _, err := FindDifficulty("nightmare"); err == nil || err.Error() != `unknown difficulty "nightmare"` {
+		t.Errorf("nightmare: got %v", err)
+	}
+}
+
+// TestDifficulties checks what each preset does to the health of enemies and
+// bosses, and to the speed and number of their bullets
+func TestDifficulties(t *testing.T) {
+	if err := LoadData(".."); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		hp      int
+		phases  []int
+		speed   float64
+		bullets int // in a hundred patterns, give or take one for rounding
+	}{
+		{"easy", 3, []int{45, 60, 75}, 0.8, 60},
+		{"normal", 4, []int{60, 80, 100}, 1, 100},
+		{"hard", 6, []int{90, 120, 150}, 1.2, 150},
+	} {
+		d, err := FindDifficulty(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWorld()
+		w.SetDifficulty(d)
+		w.spawnEnemy(&Kind{Name: "tough", HP: 4}, 100, 40, nil)
+		w.spawnBoss(Bosses["warden"])
+		if hp := w.Enemies[0].HP; hp != tt.hp {
+			t.Errorf("%s: the enemy has %d HP, want %d", tt.name, hp, tt.hp)
+		}
+		if phases := w.Boss.MaxHP(); !slices.Equal(phases, tt.phases) {
+			t.Errorf("%s: the boss phases have %v HP, want %v", tt.name, phases, tt.phases)
+		}
+		bullets := 0
+		for range 100 {
+			bullets += w.bullets()
+		}
+		if bullets < tt.bullets-1 || bullets > tt.bullets {
+			t.Errorf("%s: a hundred patterns fired %d bullets, want %d", tt.name, bullets, tt.bullets)
+		}
+		w.Bullets = append(w.Bullets, Bullet{X: 10, Y: 100, VY: 1, Life: 10, Hostile: true}, Bullet{X: 20, Y: 100, VY: -1, Life: 10})
+		w.Step(nil)
+		if hostile, own := w.Bullets[0].Y-100, 100-w.Bullets[1].Y; math.Abs(hostile-tt.speed) > 1e-9 || own != 1 {
+			t.Errorf("%s: the enemy bullet moved %g and the player's %g, want %g and 1", tt.name, hostile, own, tt.speed)
+		}
+	}
+}
+
+// TestAdaptive checks that the intensity goes down when a player is hit, and
+// back up when nobody is
+func TestAdaptive(t *testing.T) {
+	d := DefaultDifficulty
+	d.Adaptive = true
+	w := NewWorld()
+	w.SetDifficulty(d)
+	p := &w.Players[0]
+	p.Invuln, p.ShieldTime = 0, 100
+	w.hurt(0)
+	want := 1 - ADAPT_SHIELD
+	if math.Abs(w.Intensity-want) > 1e-9 {
+		t.Errorf("a hit on the shield left an intensity of %g, want %g", w.Intensity, want)
+	}
+	p.Invuln = 0
+	w.hurt(0)
+	want -= ADAPT_LIFE
+	if math.Abs(w.Intensity-want) > 1e-9 {
+		t.Errorf("losing a life left an intensity of %g, want %g", w.Intensity, want)
+	}
+	p.Invuln, p.Lives = 0, 1
+	w.hurt(0)
+	want -= ADAPT_LIFE + ADAPT_DEATH
+	if math.Abs(w.Intensity-want) > 1e-9 {
+		t.Errorf("losing the last life left an intensity of %g, want %g", w.Intensity, want)
+	}
+	for range 3 {
+		w.adapt(0)
+	}
+	if w.Intensity != MIN_INTENSITY {
+		t.Errorf("the intensity went down to %g", w.Intensity)
+	}
+	if speed := w.bulletSpeed(); math.Abs(speed-(1+(MIN_INTENSITY-1)/2)) > 1e-9 {
+		t.Errorf("at the lowest intensity, the bullets go %g times as fast", speed)
+	}
+
+	for range ADAPT_CALM {
+		w.Step(nil)
+	}
+	if w.Intensity != MIN_INTENSITY {
+		t.Errorf("the intensity went up to %g before %d calm ticks", w.Intensity, ADAPT_CALM)
+	}
+	for range int(2 * (MAX_INTENSITY - MIN_INTENSITY) / ADAPT_RISE) {
+		w.Step(nil)
+	}
+	if w.Intensity != MAX_INTENSITY {
+		t.Errorf("the intensity went up to %g, want %g", w.Intensity, MAX_INTENSITY)
+	}
+
+	// without the adaptive mode, the intensity stays at 1
+	w = NewWorld()
+	w.Players[0].Invuln = 0
+	w.hurt(0)
+	if w.Intensity != 1 {
+		t.Errorf("the intensity changed to %g without the adaptive mode", w.Intensity)
+	}
+}
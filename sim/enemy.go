@@ -0,0 +1,116 @@
+package sim
+
+import (
+	"math"
+
+	"hi/curve"
+	"hi/pattern"
+)
+
+// Kind describes a type of enemy
+type Kind struct {
+	Name    string
+	HP      int
+	Speed   float64 // pixels per tick, straight down or along the path
+	Wave    float64 // amplitude of the sideways wobble, when not following a path
+	Pattern string  // bullet pattern, fired over and over while on the screen
+	Score   int     // points for destroying it
+}
+
+// Kinds are the enemy types that level files can spawn
+var Kinds = map[string]*Kind{
+	"grunt":  {Name: "grunt", HP: 1, Speed: 0.75, Pattern: "grunt", Score: 100},
+	"weaver": {Name: "weaver", HP: 2, Speed: 0.5, Wave: 24, Pattern: "weaver", Score: 250},
+}
+
+type Enemy struct {
+	Kind    *Kind
+	X       float64
+	Y       float64
+	HP      int
+	Age     uint64
+	Start   curve.Point // spawn position, paths are relative to it
+	Path    *curve.Path
+	Action  *pattern.Runner
+	LastHit int // the player that hit it last, and gets the points
+}
+
+// spawnEnemy adds an enemy of the given kind at x, y
+func (w *World) spawnEnemy(kind *Kind, x, y float64, path *curve.Path) {
+	e := Enemy{
+		Kind:    kind,
+		X:       x,
+		Y:       y,
+		HP:      health(kind.HP, w.Difficulty.Health),
+		Start:   curve.Point{X: x, Y: y},
+		Path:    path,
+		LastHit: -1,
+	}
+	if kind.Pattern != "" {
+		e.Action = pattern.NewRunner(Patterns[kind.Pattern], math.Pi/2, 1)
+	}
+	w.Enemies = append(w.Enemies, e)
+}
+
+func (w *World) updateEnemies() {
+	alive := w.Enemies[:0]
+	for _, e := range w.Enemies {
+		if e.HP <= 0 {
+			w.award(e.LastHit, e.Kind.Score)
+			w.publish(EnemyKilled{Player: e.LastHit, Kind: e.Kind.Name, X: e.X + ENEMY_W/2, Y: e.Y + ENEMY_H/2})
+			w.drop(e.Kind.Name, e.X+ENEMY_W/2, e.Y+ENEMY_H/2)
+			continue
+		}
+		e.Age++
+		if e.Path != nil {
+			d := float64(e.Age) * e.Kind.Speed
+			if d > e.Path.Length() {
+				continue
+			}
+			p := e.Path.At(d)
+			e.X, e.Y = e.Start.X+p.X, e.Start.Y+p.Y
+		} else {
+			e.Y += e.Kind.Speed
+			e.X = e.Start.X + e.Kind.Wave*math.Sin(float64(e.Age)/30)
+		}
+		if e.Y > H+MARGIN || e.Y < -MARGIN-ENEMY_H || e.X < -MARGIN-ENEMY_W || e.X > W+MARGIN {
+			continue
+		}
+		if e.Action != nil && e.X >= 0 && e.X < W-ENEMY_W && e.Y >= 0 && e.Y < H-ENEMY_H {
+			if e.Action.Done() {
+				e.Action.Restart(Patterns[e.Kind.Pattern])
+			}
+			e.Action.Step(&emitter{w, e.X + ENEMY_W/2, e.Y + ENEMY_H/2})
+		}
+		alive = append(alive, e)
+	}
+	w.Enemies = alive
+}
+
+// hitEnemy checks if the bullet hits an enemy, and damages the enemy if so
+func (w *World) hitEnemy(b *Bullet) bool {
+	i := w.grid.first(w.Enemies, b.X, b.Y, BULLET_W, BULLET_H)
+	if i == -1 {
+		return false
+	}
+	w.Enemies[i].HP--
+	w.Enemies[i].LastHit = b.Owner
+	return true
+}
+
+// nearestEnemy returns the living enemy closest to x, y, or nil
+func (w *World) nearestEnemy(x, y float64) *Enemy {
+	var nearest *Enemy
+	best := math.Inf(1)
+	for i := range w.Enemies {
+		e := &w.Enemies[i]
+		if e.HP <= 0 {
+			continue
+		}
+		dx, dy := e.X+ENEMY_W/2-x, e.Y+ENEMY_H/2-y
+		if d := dx*dx + dy*dy; d < best {
+			nearest, best = e, d
+		}
+	}
+	return nearest
+}
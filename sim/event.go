@@ -0,0 +1,134 @@
+package sim
+
+import (
+	"fmt"
+)
+
+// Event is something that happened during a tick. The events of the last
+// tick are in World.Events, for sounds, effects and achievements, which
+// subscribe to them through a Bus.
+type Event interface {
+	event()
+	fmt.Stringer
+}
+
+// BulletFired is a shot of a player, of one bullet per weapon level, or a missile
+type BulletFired struct {
+	Player  int
+	Bullets int
+	Missile bool
+}
+
+// BulletHit is a bullet or missile of a player that hit an enemy or the boss
+type BulletHit struct {
+	Player int
+	X, Y   float64
+}
+
+// EnemyKilled is an enemy or a boss that was destroyed. The player is -1
+// if nobody hit it.
+type EnemyKilled struct {
+	Player int
+	Kind   string
+	Boss   bool
+	X, Y   float64
+}
+
+// PlayerHit is a player that was hit. The shield took the hit if Shield is
+// set, otherwise a life was lost.
+type PlayerHit struct {
+	Player int
+	Shield bool
+	Lives  int // left after the hit
+}
+
+// PickupCollected is a pickup that a player collected
+type PickupCollected struct {
+	Player int
+	Item   Item
+}
+
+// BombUsed is a bomb that a player set off
+type BombUsed struct {
+	Player int
+}
+
+// BossSpawned is a boss that entered the screen
+type BossSpawned struct {
+	Kind string
+}
+
+func (BulletFired) event()     {}
+func (BulletHit) event()       {}
+func (EnemyKilled) event()     {}
+func (PlayerHit) event()       {}
+func (PickupCollected) event() {}
+func (BombUsed) event()        {}
+func (BossSpawned) event()     {}
+
+func (e BulletFired) String() string {
+	if e.Missile {
+		return fmt.Sprintf("fired player=%d missile", e.Player+1)
+	}
+	return fmt.Sprintf("fired player=%d bullets=%d", e.Player+1, e.Bullets)
+}
+
+func (e BulletHit) String() string {
+	return fmt.Sprintf("hit player=%d x=%.1f y=%.1f", e.Player+1, e.X, e.Y)
+}
+
+func (e EnemyKilled) String() string {
+	return fmt.Sprintf("killed %s player=%d x=%.1f y=%.1f", e.Kind, e.Player+1, e.X, e.Y)
+}
+
+func (e PlayerHit) String() string {
+	return fmt.Sprintf("player-hit player=%d shield=%t lives=%d", e.Player+1, e.Shield, e.Lives)
+}
+
+func (e PickupCollected) String() string {
+	return fmt.Sprintf("collected %s player=%d", e.Item, e.Player+1)
+}
+
+func (e BombUsed) String() string {
+	return fmt.Sprintf("bomb player=%d", e.Player+1)
+}
+
+func (e BossSpawned) String() string {
+	return fmt.Sprintf("boss %s", e.Kind)
+}
+
+// publish adds an event to the events of this tick
+func (w *World) publish(e Event) {
+	w.Events = append(w.Events, e)
+}
+
+// Bus hands the events of each tick to the subscribers, in the order they
+// happened. It is not part of the world, so that snapshots and rollbacks
+// do not copy the subscribers.
+type Bus struct {
+	subscribers []func(Event)
+}
+
+// Subscribe calls fn with every event
+func (b *Bus) Subscribe(fn func(Event)) {
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// On calls fn with every event of type T
+func On[T Event](b *Bus, fn func(T)) {
+	b.Subscribe(func(e Event) {
+		if e, ok := e.(T); ok {
+			fn(e)
+		}
+	})
+}
+
+// Publish hands the events to every subscriber, which is done once after
+// every tick with World.Events
+func (b *Bus) Publish(events []Event) {
+	for _, e := range events {
+		for _, fn := range b.subscribers {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// target is an enemy that does not move or fire, and dies from one hit
+var target = &Kind{Name: "target", HP: 1}
+
+// step steps the world until match returns true for one of the events of
+// a tick, and returns those events
+func step(t *testing.T, w *World, inputs []Input, match func(Event) bool) []Event {
+	t.Helper()
+	for range 600 {
+		w.Step(inputs)
+		if slices.ContainsFunc(w.Events, match) {
+			return w.Events
+		}
+	}
+	t.Fatalf("nothing happened after 600 ticks, at tick %d", w.Tick)
+	return nil
+}
+
+func TestBulletFired(t *testing.T) {
+	w := NewWorld()
+	w.Step([]Input{Fire})
+	if !slices.Equal(w.Events, []Event{BulletFired{Player: 0, Bullets: 1}}) {
+		t.Errorf("firing published %v", w.Events)
+	}
+	w.Players[0].Weapon = 3
+	w.Step([]Input{Fire | Missile})
+	want := []Event{BulletFired{Player: 0, Bullets: 3}, BulletFired{Player: 0, Bullets: 1, Missile: true}}
+	if !slices.Equal(w.Events, want) {
+		t.Errorf("firing with three bullets and a missile published %v, want %v", w.Events, want)
+	}
+	w.Step(nil)
+	if len(w.Events) != 0 {
+		t.Errorf("the events of the last tick are still there: %v", w.Events)
+	}
+}
+
+// TestEnemyKilled checks that the player who shot the enemy gets the kill,
+// and that the hit comes before it
+func TestEnemyKilled(t *testing.T) {
+	w := NewWorld()
+	w.Step([]Input{0, Join})
+	ship := w.Players[1].Ship
+	w.spawnEnemy(target, ship.X, ship.Y-80, nil)
+	events := step(t, w, []Input{0, Fire}, func(e Event) bool {
+		_, ok := e.(EnemyKilled)
+		return ok
+	})
+	var hits []Event
+	for _, e := range events {
+		if _, ok := e.(BulletFired); !ok {
+			hits = append(hits, e)
+		}
+	}
+	killed := EnemyKilled{Player: 1, Kind: "target", X: ship.X + ENEMY_W/2, Y: ship.Y - 80 + ENEMY_H/2}
+	if len(hits) != 2 || hits[0].(BulletHit).Player != 1 || hits[1] != killed {
+		t.Errorf("the shot of player 2 published %v", events)
+	}
+	if w.Players[1].Stats.Kills != 1 || w.Players[0].Stats.Kills != 0 {
+		t.Errorf("the players have %d and %d kills", w.Players[0].Stats.Kills, w.Players[1].Stats.Kills)
+	}
+}
+
+func TestPlayerHit(t *testing.T) {
+	w := NewWorld()
+	p := &w.Players[0]
+	shoot := func() []Event {
+		p.Invuln = 0
+		w.Bullets = append(w.Bullets, Bullet{X: p.Ship.X + (SHIP_W-HITBOX)/2, Y: p.Ship.Y + (SHIP_H-HITBOX)/2, Life: 10, Hostile: true})
+		return step(t, w, nil, func(e Event) bool {
+			_, ok := e.(PlayerHit)
+			return ok
+		})
+	}
+	p.ShieldTime = 100
+	if events := shoot(); !slices.Equal(events, []Event{PlayerHit{Player: 0, Shield: true, Lives: LIVES}}) {
+		t.Errorf("a hit on the shield published %v", events)
+	}
+	if events := shoot(); !slices.Equal(events, []Event{PlayerHit{Player: 0, Lives: LIVES - 1}}) {
+		t.Errorf("a hit without the shield published %v", events)
+	}
+}
+
+func TestBus(t *testing.T) {
+	events := []Event{
+		BulletFired{Player: 0, Bullets: 1},
+		PlayerHit{Player: 1, Lives: 2},
+		BombUsed{0},
+		PlayerHit{Player: 0, Shield: true, Lives: 3},
+	}
+	var b Bus
+	var all []Event
+	var hits []PlayerHit
+	var order []string
+	b.Subscribe(func(e Event) {
+		all = append(all, e)
+		order = append(order, "all "+e.String())
+	})
+	On(&b, func(e PlayerHit) {
+		hits = append(hits, e)
+		order = append(order, "hits "+e.String())
+	})
+	b.Publish(events)
+	if !slices.Equal(all, events) {
+		t.Errorf("Subscribe got %v, want %v", all, events)
+	}
+	if want := []PlayerHit{events[1].(PlayerHit), events[3].(PlayerHit)}; !slices.Equal(hits, want) {
+		t.Errorf("On got %v, want %v", hits, want)
+	}
+	// every subscriber gets an event before the next one is handed out
+	want := []string{
+		"all fired player=1 bullets=1",
+		"all player-hit player=2 shield=false lives=2",
+		"hits player-hit player=2 shield=false lives=2",
+		"all bomb player=1",
+		"all player-hit player=1 shield=true lives=3",
+		"hits player-hit player=1 shield=true lives=3",
+	}
+	if !slices.Equal(order, want) {
+		t.Errorf("the events were handed out as %q, want %q", order, want)
+	}
+}
@@ -0,0 +1,82 @@
+package sim
+
+const (
+	// CELL is the size of the cells of the spatial hash that bullets use to find enemies
+	CELL = 32
+
+	GRID_COLS = (W+2*MARGIN)/CELL + 1
+	GRID_ROWS = (H+2*MARGIN)/CELL + 1
+)
+
+// grid is a spatial hash of the enemies, so that each bullet only has to
+// check the enemies near it. It is built again at the start of every tick,
+// and is not part of the world state.
+type grid struct {
+	cells [GRID_COLS * GRID_ROWS][]int // indexes into World.Enemies, in order
+}
+
+// Cell is a cell of the spatial hash, for the debug overlay
+type Cell struct {
+	X, Y    float64
+	Enemies int
+}
+
+// cellRange returns the columns and rows of the cells that a rectangle touches.
+// Everything outside of the grid goes into the cells along the edges.
+func cellRange(x, y, w, h float64) (c0, r0, c1, r1 int) {
+	col := func(x float64) int { return min(max(int((x+MARGIN)/CELL), 0), GRID_COLS-1) }
+	row := func(y float64) int { return min(max(int((y+MARGIN)/CELL), 0), GRID_ROWS-1) }
+	return col(x), row(y), col(x + w), row(y + h)
+}
+
+// build puts every enemy into the cells it touches
+func (g *grid) build(enemies []Enemy) {
+	for i := range g.cells {
+		g.cells[i] = g.cells[i][:0]
+	}
+	for i := range enemies {
+		c0, r0, c1, r1 := cellRange(enemies[i].X, enemies[i].Y, ENEMY_W, ENEMY_H)
+		for r := r0; r <= r1; r++ {
+			for c := c0; c <= c1; c++ {
+				g.cells[r*GRID_COLS+c] = append(g.cells[r*GRID_COLS+c], i)
+			}
+		}
+	}
+}
+
+// first returns the lowest index of a living enemy that overlaps the
+// rectangle, or -1. The lowest index wins, so that the result is the same
+// as checking every enemy in order.
+func (g *grid) first(enemies []Enemy, x, y, w, h float64) int {
+	found := -1
+	c0, r0, c1, r1 := cellRange(x, y, w, h)
+	for r := r0; r <= r1; r++ {
+		for c := c0; c <= c1; c++ {
+			for _, i := range g.cells[r*GRID_COLS+c] {
+				if found != -1 && i >= found {
+					break
+				}
+				e := &enemies[i]
+				if e.HP > 0 && overlaps(x, y, w, h, e.X, e.Y, ENEMY_W, ENEMY_H) {
+					found = i
+				}
+			}
+		}
+	}
+	return found
+}
+
+// Cells returns the cells of the spatial hash that have enemies in them
+func (w *World) Cells() []Cell {
+	var cells []Cell
+	for i, cell := range w.grid.cells {
+		if len(cell) > 0 {
+			cells = append(cells, Cell{
+				X:       float64(i%GRID_COLS*CELL - MARGIN),
+				Y:       float64(i/GRID_COLS*CELL - MARGIN),
+				Enemies: len(cell),
+			})
+		}
+	}
+	return cells
+}
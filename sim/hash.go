@@ -0,0 +1,119 @@
+package sim
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+
+	"hi/pattern"
+)
+
+// Hash returns a checksum of the world state, for checking that replays and
+// online games play out the same way every time
+func (w *World) Hash() uint64 {
+	h := hasher{fnv.New64a()}
+	h.uint(w.Tick, w.Rand.State, w.levelStart)
+	h.Write([]byte{w.Background.R, w.Background.G, w.Background.B})
+	// games on the default difficulty hash the same as before there were others
+	if w.Difficulty != DefaultDifficulty {
+		d := w.Difficulty
+		h.Write([]byte(d.Name))
+		h.float(d.Health, d.BulletSpeed, d.Density, w.Intensity, w.density)
+		h.bool(d.Adaptive)
+		h.uint(w.lastHit)
+	}
+	for _, p := range w.Players {
+		h.bool(p.Joined, p.Focused)
+		h.float(p.Ship.X, p.Ship.Y, p.Vel.X, p.Vel.Y)
+		h.int(p.Score, p.Lives, p.Bombs, p.Weapon, p.dirX, p.dirY)
+		h.uint(p.Cooldown, p.Invuln, p.ShieldTime, p.SpeedTime, uint64(p.prevInput))
+		h.uint(p.Stats.Alive)
+		h.int(p.Stats.Fired, p.Stats.Hits, p.Stats.Kills, p.Stats.Damage)
+	}
+	for _, bullets := range [][]Bullet{w.Bullets, w.Missiles} {
+		h.int(len(bullets))
+		for _, b := range bullets {
+			h.float(b.X, b.Y, b.VX, b.VY, b.Homing)
+			h.uint(b.Life)
+			h.bool(b.Hostile)
+			h.int(b.Owner)
+			h.runner(b.Action)
+		}
+	}
+	h.int(len(w.Enemies))
+	for _, e := range w.Enemies {
+		h.Write([]byte(e.Kind.Name))
+		h.float(e.X, e.Y, e.Start.X, e.Start.Y)
+		h.int(e.HP, e.LastHit)
+		h.uint(e.Age)
+		h.runner(e.Action)
+	}
+	if b := w.Boss; b != nil {
+		h.Write([]byte(b.Kind.Name))
+		h.float(b.X, b.Y)
+		h.int(b.Phase, b.HP, b.LastHit)
+		h.uint(b.Age, b.Hit)
+		for _, p := range b.Parts {
+			h.int(p.HP)
+		}
+		for _, r := range b.Attacks {
+			h.runner(r)
+		}
+	}
+	h.int(len(w.Pickups))
+	for _, p := range w.Pickups {
+		h.int(int(p.Item))
+		h.float(p.X, p.Y, p.VX, p.VY)
+		h.uint(p.Age)
+	}
+	if b := w.Blast; b != nil {
+		h.float(b.X, b.Y)
+		h.uint(b.Age)
+		h.int(b.Owner)
+	}
+	return h.Sum64()
+}
+
+type hasher struct {
+	hash.Hash64
+}
+
+func (h hasher) uint(values ...uint64) {
+	var buf []byte
+	for _, v := range values {
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+	}
+	h.Write(buf)
+}
+
+func (h hasher) int(values ...int) {
+	for _, v := range values {
+		h.uint(uint64(v))
+	}
+}
+
+func (h hasher) float(values ...float64) {
+	for _, v := range values {
+		h.uint(math.Float64bits(v))
+	}
+}
+
+func (h hasher) bool(values ...bool) {
+	for _, v := range values {
+		if v {
+			h.uint(1)
+		} else {
+			h.uint(0)
+		}
+	}
+}
+
+func (h hasher) runner(r *pattern.Runner) {
+	if r == nil {
+		h.uint(0)
+		return
+	}
+	h.uint(1)
+	r.Hash(h)
+}
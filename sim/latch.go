@@ -0,0 +1,40 @@
+package sim
+
+// PRESSES are the buttons that are a press and not held down, like the
+// bullets of the fire button, which must only be seen by one tick
+const PRESSES = Fire
+
+// Latch keeps the inputs of the players from the frames of the game until
+// the world is stepped. Frames and ticks do not line up when the game is
+// paused, slowed down or sped up: buttons that are pressed in a frame
+// without a tick are kept for the next tick, and of the ticks in one frame,
+// only the first sees the PRESSES.
+type Latch struct {
+	held    [MAX_PLAYERS]Input // the buttons held down in the last frame
+	pending [MAX_PLAYERS]Input // what the next tick sees
+	taken   bool               // a tick has seen the pending inputs
+}
+
+// Add adds the inputs of a frame
+func (l *Latch) Add(inputs []Input) {
+	for i := range l.pending {
+		var in Input
+		if i < len(inputs) {
+			in = inputs[i]
+		}
+		if l.taken {
+			l.pending[i] = 0
+		}
+		l.pending[i] |= in
+		l.held[i] = in &^ PRESSES
+	}
+	l.taken = false
+}
+
+// Take returns the inputs for a tick
+func (l *Latch) Take() []Input {
+	inputs := make([]Input, MAX_PLAYERS)
+	copy(inputs, l.pending[:])
+	l.pending, l.taken = l.held, true
+	return inputs
+}
@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestLatch plays frames of the first player through a latch, with as many
+// ticks as the time control gives each frame, and checks what the ticks see
+func TestLatch(t *testing.T) {
+	type frame struct {
+		in    Input
+		ticks int
+	}
+	for _, tt := range []struct {
+		name   string
+		frames []frame
+		want   []Input
+	}{
+		{"one tick a frame", []frame{{Left | Fire, 1}, {Left, 1}, {0, 1}}, []Input{Left | Fire, Left, 0}},
+		{"a press while paused", []frame{{Fire, 0}, {0, 0}, {0, 1}, {0, 1}}, []Input{Fire, 0}},
+		{"a tap between ticks", []frame{{0, 1}, {Bomb, 0}, {0, 0}, {0, 1}, {0, 1}}, []Input{0, Bomb, 0}},
+		{"fast forward", []frame{{Left | Fire, 3}, {Left, 2}}, []Input{Left | Fire, Left, Left, Left, Left}},
+		{"a release", []frame{{Right | Focus, 1}, {Right, 0}, {0, 1}}, []Input{Right | Focus, Right}},
+	} {
+		var l Latch
+		var got []Input
+		for _, f := range tt.frames {
+			l.Add([]Input{f.in})
+			for range f.ticks {
+				got = append(got, l.Take()[0])
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: the ticks saw %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestLatchWorld checks that a bomb that is tapped while the game is paused
+// goes off, and that a shot fires once when the game is sped up
+func TestLatchWorld(t *testing.T) {
+	w := NewWorld()
+	var l Latch
+	l.Add([]Input{Bomb})
+	l.Add(nil)
+	w.Step(l.Take())
+	if !slices.Contains(w.Events, Event(BombUsed{0})) {
+		t.Errorf("the bomb that was tapped while paused published %v", w.Events)
+	}
+	l.Add([]Input{Fire})
+	fired := 0
+	for range 4 {
+		w.Step(l.Take())
+		for _, e := range w.Events {
+			if _, ok := e.(BulletFired); ok {
+				fired++
+			}
+		}
+	}
+	if fired != 1 {
+		t.Errorf("one press fired %d times in four ticks of a frame", fired)
+	}
+}
@@ -0,0 +1,41 @@
+package sim
+
+import (
+	"hi/level"
+)
+
+// Load validates the level against the known enemy kinds and bosses,
+// and starts running it from the current tick
+func (w *World) Load(l *level.Level) error {
+	for _, e := range l.Events {
+		switch e.Kind {
+		case level.Spawn, level.Formation:
+			if Kinds[e.Enemy] == nil {
+				return l.Errorf(e.Line, "unknown enemy %q", e.Enemy)
+			}
+		case level.Boss:
+			if Bosses[e.Boss] == nil {
+				return l.Errorf(e.Line, "unknown boss %q", e.Boss)
+			}
+		}
+	}
+	w.Level = level.NewRunner(l)
+	w.levelStart = w.Tick
+	return nil
+}
+
+// runLevel triggers the level events that are due at this tick
+func (w *World) runLevel() {
+	for _, e := range w.Level.Due(w.Tick - w.levelStart) {
+		switch e.Kind {
+		case level.Spawn, level.Formation:
+			for _, p := range e.Positions() {
+				w.spawnEnemy(Kinds[e.Enemy], p.X, p.Y, w.Level.Level.Paths[e.Path])
+			}
+		case level.Background:
+			w.Background = e.Color
+		case level.Boss:
+			w.spawnBoss(Bosses[e.Boss])
+		}
+	}
+}
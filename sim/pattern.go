@@ -0,0 +1,90 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"hi/pattern"
+)
+
+// Patterns are the bullet patterns that enemies and bosses fire, see UsePatterns
+var Patterns pattern.Set
+
+// UsePatterns sets the bullet patterns, after checking that every pattern
+// that an enemy or boss refers to is in the set. All the patterns that are
+// missing are reported at once, in order.
+func UsePatterns(set pattern.Set) error {
+	var missing []string
+	check := func(who, name string) {
+		if name != "" && set[name] == nil {
+			missing = append(missing, fmt.Sprintf("%s uses unknown pattern %q", who, name))
+		}
+	}
+	for _, k := range Kinds {
+		check(k.Name, k.Pattern)
+	}
+	for _, b := range Bosses {
+		for i, phase := range b.Phases {
+			for _, a := range phase.Attacks {
+				check(fmt.Sprintf("%s phase %d", b.Name, i+1), a.Pattern)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		errs := make([]error, len(missing))
+		for i, m := range missing {
+			errs[i] = errors.New(m)
+		}
+		return errors.Join(errs...)
+	}
+	Patterns = set
+	return nil
+}
+
+// emitter is the environment of a pattern that is run from x, y
+type emitter struct {
+	w *World
+	x float64
+	y float64
+}
+
+// Aim returns the direction towards the nearest player, or straight down
+func (e *emitter) Aim() float64 {
+	p := e.w.nearestPlayer(e.x, e.y)
+	if p == nil {
+		return math.Pi / 2
+	}
+	return math.Atan2(p.Ship.Y+SHIP_H/2-e.y, p.Ship.X+SHIP_W/2-e.x)
+}
+
+// Fire fires as many bullets as the difficulty asks for, fanned out around dir
+func (e *emitter) Fire(dir, speed float64, action *pattern.Action) {
+	n := e.w.bullets()
+	for i := range n {
+		e.w.fireBullet(e.x, e.y, dir+(float64(i)-float64(n-1)/2)*DENSITY_SPREAD, speed, action)
+	}
+}
+
+// fireBullet fires a hostile bullet with its middle at x, y. The bullet is
+// added at the end of the tick, so that patterns can fire while the
+// bullets are being updated. When the pool of bullets is full, nothing is fired.
+func (w *World) fireBullet(x, y, dir, speed float64, action *pattern.Action) {
+	if len(w.Bullets)+len(w.fired) >= MAX_BULLETS {
+		return
+	}
+	b := Bullet{
+		X:       x - ENEMY_BULLET_W/2,
+		Y:       y - ENEMY_BULLET_H/2,
+		VX:      speed * math.Cos(dir),
+		VY:      speed * math.Sin(dir),
+		Life:    ENEMY_BULLET_LIFE,
+		Hostile: true,
+	}
+	if action != nil {
+		b.Action = pattern.NewRunner(action, dir, speed)
+	}
+	w.fired = append(w.fired, b)
+}
@@ -0,0 +1,37 @@
+package sim
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"hi/pattern"
+)
+
+// TestUsePatterns checks that every missing pattern is reported, in order
+func TestUsePatterns(t *testing.T) {
+	want := 0
+	for _, k := range Kinds {
+		if k.Pattern != "" {
+			want++
+		}
+	}
+	for _, b := range Bosses {
+		for _, phase := range b.Phases {
+			want += len(phase.Attacks)
+		}
+	}
+	for range 5 {
+		err := UsePatterns(pattern.Set{})
+		if err == nil {
+			t.Fatal("no error without patterns")
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != want || !slices.IsSorted(lines) {
+			t.Fatalf("got %d errors, want %d in order:\n%s", len(lines), want, err)
+		}
+		if !slices.Contains(lines, `grunt uses unknown pattern "grunt"`) {
+			t.Errorf("the grunt is not reported:\n%s", err)
+		}
+	}
+}
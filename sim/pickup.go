@@ -0,0 +1,163 @@
+package sim
+
+import (
+	"math"
+	"sort"
+
+	"hi/drops"
+)
+
+// Item is a type of pickup
+type Item uint8
+
+const (
+	WeaponItem Item = iota // one more bullet per shot
+	ShieldItem             // absorbs one hit, for a while
+	SpeedItem              // faster movement, for a while
+	BombItem               // one more bomb
+	LifeItem               // one more life
+	NUM_ITEMS
+)
+
+var itemNames = [NUM_ITEMS]string{"weapon", "shield", "speed", "bomb", "life"}
+
+func (i Item) String() string {
+	return itemNames[i]
+}
+
+const (
+	PICKUP_W = 8
+	PICKUP_H = 8
+
+	PICKUP_LIFE  = 600  // ticks before an uncollected pickup disappears
+	PICKUP_SPEED = 0.5  // how fast pickups fall
+	MAGNET       = 48   // pickups closer to the ship than this are pulled towards it
+	MAGNET_PULL  = 0.15 // acceleration towards the ship, per tick
+
+	SHIELD_TICKS = 600
+	SPEED_TICKS  = 480
+
+	MAX_WEAPON = 3
+	MAX_LIVES  = 9
+	MAX_BOMBS  = 9
+)
+
+type Pickup struct {
+	Item Item
+	X    float64
+	Y    float64
+	VX   float64
+	VY   float64
+	Age  uint64
+}
+
+// Drops are the drop tables, see UseDrops
+var Drops drops.Tables
+
+// UseDrops sets the drop tables, after checking that they only name known
+// enemies, bosses and items
+func UseDrops(tables drops.Tables) error {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := tables[name]
+		if Kinds[name] == nil && Bosses[name] == nil {
+			return t.Errorf("unknown enemy %q", name)
+		}
+		for _, e := range t.Items {
+			if _, ok := itemByName(e.Item); !ok {
+				return t.Errorf("unknown item %q", e.Item)
+			}
+		}
+	}
+	Drops = tables
+	return nil
+}
+
+func itemByName(name string) (Item, bool) {
+	for i, n := range itemNames {
+		if n == name {
+			return Item(i), true
+		}
+	}
+	return 0, false
+}
+
+// drop rolls the drop table of the named enemy, and drops the item at x, y
+func (w *World) drop(name string, x, y float64) {
+	t := Drops[name]
+	if t == nil || w.Rand.Float64() >= t.Chance {
+		return
+	}
+	item, _ := itemByName(t.Pick(w.Rand.Intn(t.Total)))
+	w.Pickups = append(w.Pickups, Pickup{
+		Item: item,
+		X:    x - PICKUP_W/2,
+		Y:    y - PICKUP_H/2,
+		VY:   PICKUP_SPEED,
+	})
+}
+
+func (w *World) updatePickups() {
+	alive := w.Pickups[:0]
+	for _, p := range w.Pickups {
+		p.Age++
+		cx, cy := p.X+PICKUP_W/2, p.Y+PICKUP_H/2
+		pulled := false
+		if pl := w.nearestPlayer(cx, cy); pl != nil {
+			dx, dy := pl.Ship.X+SHIP_W/2-cx, pl.Ship.Y+SHIP_H/2-cy
+			if d := math.Hypot(dx, dy); d < MAGNET && d > 0 {
+				p.VX += dx / d * MAGNET_PULL
+				p.VY += dy / d * MAGNET_PULL
+				pulled = true
+			}
+		}
+		if !pulled {
+			// drift back to falling straight down
+			p.VX *= 0.95
+			p.VY += (PICKUP_SPEED - p.VY) * 0.05
+		}
+		p.X += p.VX
+		p.Y += p.VY
+		if i := w.collector(&p); i >= 0 {
+			w.Players[i].collect(p.Item)
+			w.publish(PickupCollected{i, p.Item})
+			continue
+		}
+		if p.Age > PICKUP_LIFE || p.Y > H {
+			continue
+		}
+		alive = append(alive, p)
+	}
+	w.Pickups = alive
+}
+
+// collector returns the first player whose ship touches the pickup, or -1
+func (w *World) collector(p *Pickup) int {
+	for i := range w.Players {
+		pl := &w.Players[i]
+		if pl.Joined && overlaps(p.X, p.Y, PICKUP_W, PICKUP_H, pl.Ship.X, pl.Ship.Y, SHIP_W, SHIP_H) {
+			return i
+		}
+	}
+	return -1
+}
+
+// collect gives the player the effect of the item
+func (p *Player) collect(item Item) {
+	switch item {
+	case WeaponItem:
+		p.Weapon = min(p.Weapon+1, MAX_WEAPON)
+	case ShieldItem:
+		p.ShieldTime = SHIELD_TICKS
+	case SpeedItem:
+		p.SpeedTime = SPEED_TICKS
+	case BombItem:
+		p.Bombs = min(p.Bombs+1, MAX_BOMBS)
+	case LifeItem:
+		p.Lives = min(p.Lives+1, MAX_LIVES)
+	}
+}
@@ -0,0 +1,166 @@
+package sim
+
+import (
+	"math"
+)
+
+// Player is the state of one player, and the ship of that player
+type Player struct {
+	Joined     bool
+	Ship       Vec2
+	Vel        Vec2
+	Focused    bool
+	Score      int
+	Lives      int
+	Bombs      int
+	Weapon     int    // number of bullets per shot
+	Cooldown   uint64 // ticks until the next missile can be fired
+	Invuln     uint64 // ticks left of invulnerability
+	ShieldTime uint64 // ticks left of the shield
+	SpeedTime  uint64 // ticks left of the speed boost
+	Stats      Stats
+	God        bool // never hurt, a cheat for testing
+	prevInput  Input
+	dirX       int // the direction that won when both left and right were held
+	dirY       int
+}
+
+// Stats are counted over the whole game, for balancing
+type Stats struct {
+	Alive  uint64 // ticks spent in the game
+	Fired  int    // bullets and missiles fired
+	Hits   int    // bullets and missiles that hit an enemy or the boss
+	Kills  int    // enemies and bosses destroyed
+	Damage int    // hits taken, including the ones the shield absorbed
+}
+
+// join puts player i into the game, at the bottom of the screen. The score
+// is kept, so that a player can continue after losing all lives.
+func (w *World) join(i int) {
+	p := &w.Players[i]
+	*p = Player{
+		Joined: true,
+		Ship:   Vec2{float64(W*(i+1)/(MAX_PLAYERS+1)) - SHIP_W/2, H - SHIP_H - 16},
+		Score:  p.Score,
+		Stats:  p.Stats,
+		God:    p.God,
+		Lives:  LIVES,
+		Bombs:  BOMBS,
+		Weapon: 1,
+		Invuln: INVULNERABLE,
+	}
+}
+
+// stepPlayer handles the input of player i
+func (w *World) stepPlayer(i int, in Input) {
+	p := &w.Players[i]
+	if !p.Joined {
+		if in.Has(Join) && !p.prevInput.Has(Join) {
+			w.join(i)
+		}
+		p.prevInput = in
+		return
+	}
+	if in.Has(Leave) {
+		p.Joined = false
+		p.prevInput = in
+		return
+	}
+	p.Stats.Alive++
+	if in.Has(Bomb) && !p.prevInput.Has(Bomb) {
+		w.bomb(i)
+	}
+	w.moveShip(p, in)
+	if in.Has(Fire) {
+		w.fire(i)
+	}
+	if p.Cooldown > 0 {
+		p.Cooldown--
+	} else if in.Has(Missile) {
+		m := Bullet{X: p.Ship.X + SHIP_W/2 - 1, Y: p.Ship.Y, VY: -MISSILE_SPEED, Life: MISSILE_LIFE, Homing: MISSILE_TURN, Owner: i}
+		w.Missiles = append(w.Missiles, m)
+		p.Cooldown = MISSILE_COOLDOWN
+		p.Stats.Fired++
+		w.publish(BulletFired{Player: i, Bullets: 1, Missile: true})
+	}
+	if p.Invuln > 0 {
+		p.Invuln--
+	}
+	if p.ShieldTime > 0 {
+		p.ShieldTime--
+	}
+	if p.SpeedTime > 0 {
+		p.SpeedTime--
+	}
+}
+
+// fire fires one bullet per weapon level, spread out a little
+func (w *World) fire(i int) {
+	p := &w.Players[i]
+	n := 0
+	for ; n < p.Weapon && len(w.Bullets) < MAX_BULLETS; n++ {
+		o := float64(n) - float64(p.Weapon-1)/2
+		b := Bullet{X: p.Ship.X + SHIP_W/2 - 1 + o*4, Y: p.Ship.Y, VX: o * 0.2, VY: -1, Life: BULLET_LIFE, Owner: i}
+		w.Bullets = append(w.Bullets, b)
+		p.Stats.Fired++
+	}
+	if n > 0 {
+		w.publish(BulletFired{Player: i, Bullets: n})
+	}
+}
+
+// hitbox returns true if the rectangle overlaps the hitbox of the ship
+func (p *Player) hitbox(x, y, width, height float64) bool {
+	if !p.Joined {
+		return false
+	}
+	return overlaps(x, y, width, height, p.Ship.X+(SHIP_W-HITBOX)/2, p.Ship.Y+(SHIP_H-HITBOX)/2, HITBOX, HITBOX)
+}
+
+// hurt takes a life of player i, unless the ship is invulnerable or shielded.
+// The player drops out of the game after losing the last life.
+func (w *World) hurt(i int) {
+	p := &w.Players[i]
+	if p.Invuln > 0 || p.God {
+		return
+	}
+	p.Stats.Damage++
+	w.adapt(i)
+	if p.ShieldTime > 0 {
+		p.ShieldTime = 0
+		p.Invuln = INVULNERABLE / 2
+		w.publish(PlayerHit{Player: i, Shield: true, Lives: p.Lives})
+		return
+	}
+	p.Lives--
+	p.Invuln = INVULNERABLE
+	if p.Lives <= 0 {
+		p.Joined = false
+	}
+	w.publish(PlayerHit{Player: i, Lives: p.Lives})
+}
+
+// nearestPlayer returns the player in the game that is closest to x, y, or nil
+func (w *World) nearestPlayer(x, y float64) *Player {
+	var nearest *Player
+	best := math.Inf(1)
+	for i := range w.Players {
+		p := &w.Players[i]
+		if !p.Joined {
+			continue
+		}
+		dx, dy := p.Ship.X+SHIP_W/2-x, p.Ship.Y+SHIP_H/2-y
+		if d := dx*dx + dy*dy; d < best {
+			nearest, best = p, d
+		}
+	}
+	return nearest
+}
+
+// award credits player i with destroying something that is worth points
+func (w *World) award(i, points int) {
+	if i >= 0 && i < MAX_PLAYERS {
+		w.Players[i].Score += points
+		w.Players[i].Stats.Kills++
+	}
+}
@@ -0,0 +1,47 @@
+package sim
+
+import "testing"
+
+// TestCoop has a second player join, lose their lives, continue and leave,
+// while the first player plays on
+func TestCoop(t *testing.T) {
+	w := NewWorld()
+	p1, p2 := &w.Players[0], &w.Players[1]
+	if !p1.Joined || p2.Joined {
+		t.Fatal("the game does not start with only the first player")
+	}
+	w.Step([]Input{0, Join})
+	if !p2.Joined || p2.Lives != LIVES || p2.Bombs != BOMBS || p2.Ship == p1.Ship {
+		t.Fatalf("the second player joined as %+v", *p2)
+	}
+	p2.Score = 500
+	for range LIVES {
+		p2.Invuln = 0
+		w.hurt(1)
+	}
+	if p2.Joined || w.GameOver() {
+		t.Errorf("after losing all lives, the second player is joined: %v, and the game is over: %v", p2.Joined, w.GameOver())
+	}
+	// join has to be pressed again to continue
+	w.Step([]Input{0, Join})
+	if p2.Joined {
+		t.Error("holding join continued the game")
+	}
+	w.Step(nil)
+	w.Step([]Input{0, Join})
+	if !p2.Joined || p2.Lives != LIVES || p2.Score != 500 {
+		t.Errorf("the second player continued with %d lives and %d points, want %d and 500", p2.Lives, p2.Score, LIVES)
+	}
+	if p1.Lives != LIVES || p1.Score != 0 {
+		t.Errorf("the first player has %d lives and %d points", p1.Lives, p1.Score)
+	}
+
+	w.Step([]Input{Leave, 0})
+	if p1.Joined || !p2.Joined || w.GameOver() {
+		t.Error("the first player did not leave, or the game is over with the second one playing")
+	}
+	w.Step([]Input{0, Leave})
+	if !w.GameOver() {
+		t.Error("the game is not over without players")
+	}
+}
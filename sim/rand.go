@@ -0,0 +1,27 @@
+package sim
+
+// Rand is a small deterministic random number generator (splitmix64).
+// It is part of the world state, so that replays and snapshots give the
+// same numbers every time.
+type Rand struct {
+	State uint64
+}
+
+// Uint64 returns the next random number
+func (r *Rand) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	z := r.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Float64 returns a random number in [0, 1)
+func (r *Rand) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Intn returns a random number in [0, n)
+func (r *Rand) Intn(n int) int {
+	return int(r.Uint64() % uint64(n))
+}
@@ -0,0 +1,89 @@
+package sim
+
+import (
+	"math"
+)
+
+// Physics configures how the ship moves
+type Physics struct {
+	Accel    float64 // added to the velocity every tick while a direction is held
+	MaxSpeed float64 // in pixels per tick
+	Drag     float64 // fraction of the velocity that is lost every tick without input
+	Focus    float64 // max speed multiplier while Focus is held
+	Boost    float64 // max speed multiplier while the speed pickup lasts
+}
+
+// DefaultPhysics is what NewWorld uses
+var DefaultPhysics = Physics{
+	Accel:    0.3,
+	MaxSpeed: 1.5,
+	Drag:     0.25,
+	Focus:    0.4,
+	Boost:    1.6,
+}
+
+// axis returns -1, 0 or 1 for a pair of opposing directions. When both are
+// held, the one that was pressed last wins, and prev is the previous result.
+func axis(in, prevIn, neg, pos Input, prev int) int {
+	switch {
+	case in.Has(neg) && in.Has(pos):
+		if !prevIn.Has(neg) {
+			return -1
+		}
+		if !prevIn.Has(pos) {
+			return 1
+		}
+		return prev
+	case in.Has(neg):
+		return -1
+	case in.Has(pos):
+		return 1
+	}
+	return 0
+}
+
+// moveShip accelerates the ship in the held direction, with the same speed
+// diagonally as straight, and lets it slow down when nothing is held
+func (w *World) moveShip(pl *Player, in Input) {
+	pl.dirX = axis(in, pl.prevInput, Left, Right, pl.dirX)
+	pl.dirY = axis(in, pl.prevInput, Up, Down, pl.dirY)
+	pl.prevInput = in
+	pl.Focused = in.Has(Focus)
+	pl.Ship, pl.Vel = w.Physics.Move(pl.Ship, pl.Vel, pl.dirX, pl.dirY, pl.Focused, pl.SpeedTime > 0)
+}
+
+// Move returns the position and velocity of a ship after one tick of
+// holding the direction dirX, dirY, which are -1, 0 or 1. The ship stays on
+// the screen.
+func (p *Physics) Move(pos, vel Vec2, dirX, dirY int, focused, boosted bool) (Vec2, Vec2) {
+	maxSpeed := p.MaxSpeed
+	if focused {
+		maxSpeed *= p.Focus
+	}
+	if boosted {
+		maxSpeed *= p.Boost
+	}
+
+	dx, dy := float64(dirX), float64(dirY)
+	if dx != 0 && dy != 0 {
+		dx, dy = dx*math.Sqrt2/2, dy*math.Sqrt2/2
+	}
+	if dx == 0 && dy == 0 {
+		vel.X *= 1 - p.Drag
+		vel.Y *= 1 - p.Drag
+		if math.Hypot(vel.X, vel.Y) < 0.01 {
+			vel = Vec2{}
+		}
+	} else {
+		vel.X += dx * p.Accel
+		vel.Y += dy * p.Accel
+	}
+	if speed := math.Hypot(vel.X, vel.Y); speed > maxSpeed {
+		vel.X *= maxSpeed / speed
+		vel.Y *= maxSpeed / speed
+	}
+
+	pos.X = math.Max(0, math.Min(W-SHIP_W, pos.X+vel.X))
+	pos.Y = math.Max(0, math.Min(H-SHIP_H, pos.Y+vel.Y))
+	return pos, vel
+}
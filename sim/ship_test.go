@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMove checks the top speed straight and diagonally, with focus and the
+// speed boost, and that the ship slows down to a stop
+func TestMove(t *testing.T) {
+	p := DefaultPhysics
+	start := Vec2{X: W / 2, Y: H / 2}
+	for _, tt := range []struct {
+		name             string
+		dirX, dirY       int
+		focused, boosted bool
+		speed            float64
+	}{
+		{"right", 1, 0, false, false, p.MaxSpeed},
+		{"up and left", -1, -1, false, false, p.MaxSpeed},
+		{"focused", 0, 1, true, false, p.MaxSpeed * p.Focus},
+		{"boosted", 1, 1, false, true, p.MaxSpeed * p.Boost},
+	} {
+		pos, vel := start, Vec2{}
+		for range 20 {
+			pos, vel = p.Move(pos, vel, tt.dirX, tt.dirY, tt.focused, tt.boosted)
+		}
+		if speed := math.Hypot(vel.X, vel.Y); math.Abs(speed-tt.speed) > 1e-9 {
+			t.Errorf("%s: the top speed is %g, want %g", tt.name, speed, tt.speed)
+		}
+		if math.Signbit(vel.X) != (tt.dirX < 0) || math.Signbit(vel.Y) != (tt.dirY < 0) || (tt.dirX == 0) != (vel.X == 0) {
+			t.Errorf("%s: the ship moves %v", tt.name, vel)
+		}
+		stop := 0
+		for ; vel != (Vec2{}) && stop < 100; stop++ {
+			pos, vel = p.Move(pos, vel, 0, 0, false, false)
+		}
+		if stop == 0 || stop == 100 {
+			t.Errorf("%s: the ship took %d ticks to stop", tt.name, stop)
+		}
+	}
+}
+
+func TestMoveEdges(t *testing.T) {
+	p := DefaultPhysics
+	pos, vel := Vec2{X: 1, Y: H - SHIP_H - 1}, Vec2{}
+	for range 10 {
+		pos, vel = p.Move(pos, vel, -1, 1, false, false)
+	}
+	if pos != (Vec2{X: 0, Y: H - SHIP_H}) {
+		t.Errorf("the ship is at %v, want the bottom left corner", pos)
+	}
+}
+
+// TestOpposite checks that of two opposing directions, the one that was
+// pressed last wins
+func TestOpposite(t *testing.T) {
+	w := NewWorld()
+	p := &w.Players[0]
+	for _, tt := range []struct {
+		in   Input
+		dirX int
+	}{
+		{Left, -1},
+		{Left | Right, 1},
+		{Left | Right, 1},
+		{Right, 1},
+		{Left | Right, -1},
+		{Left | Right | Up, -1},
+		{0, 0},
+		{Left | Right, -1},
+	} {
+		w.Step([]Input{tt.in})
+		if p.dirX != tt.dirX {
+			t.Fatalf("holding %b after tick %d goes %d, want %d", tt.in, w.Tick, p.dirX, tt.dirX)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package sim is the game simulation. It does not know about rendering or
+// input devices, so that it can be stepped deterministically, one tick at a time.
+package sim
+
+import (
+	"image/color"
+
+	"hi/level"
+)
+
+const (
+	W = 320
+	H = 240
+
+	SHIP_W = 16
+	SHIP_H = 16
+
+	BULLET_W = 2
+	BULLET_H = 2
+
+	ENEMY_W = 16
+	ENEMY_H = 16
+
+	BULLET_LIFE = 100
+
+	ENEMY_BULLET_W    = 4
+	ENEMY_BULLET_H    = 4
+	ENEMY_BULLET_LIFE = 1200
+
+	// MAX_BULLETS is the size of the bullet pool
+	MAX_BULLETS = 4096
+
+	// HITBOX is the size of the part of the ship that can be hit, in the middle of the sprite
+	HITBOX = 4
+
+	MAX_PLAYERS  = 4
+	LIVES        = 3
+	INVULNERABLE = 120 // ticks of invulnerability after being hit
+
+	MISSILE_LIFE     = 180
+	MISSILE_COOLDOWN = 30
+	MISSILE_SPEED    = 2
+	MISSILE_TURN     = 0.06
+
+	// MARGIN is how far outside of the screen enemies may go before they are removed
+	MARGIN = 64
+)
+
+type Vec2 struct {
+	X float64
+	Y float64
+}
+
+// Input is the state of the controls of one player for a single tick
+type Input uint16
+
+const (
+	Left Input = 1 << iota
+	Right
+	Up
+	Down
+	Fire
+	Missile
+	Focus // move slowly, for dodging
+	Bomb
+	Join  // join the game, or continue after losing all lives
+	Leave // drop out of the game
+)
+
+// Has returns true if all the given buttons are held
+func (in Input) Has(buttons Input) bool {
+	return in&buttons == buttons
+}
+
+// World is the complete state of the simulation
+type World struct {
+	Tick       uint64
+	Players    [MAX_PLAYERS]Player
+	Physics    Physics
+	Bullets    []Bullet
+	Missiles   []Bullet
+	Enemies    []Enemy
+	Boss       *Boss
+	Pickups    []Pickup
+	Blast      *Blast
+	Rand       Rand
+	Seed       uint64 // what Rand started from
+	Difficulty Difficulty
+	Intensity  float64 // how hard the adaptive difficulty pushes, 1 unless it is adaptive
+	Background color.RGBA
+	Level      level.Runner
+	levelStart uint64
+	fired      []Bullet // hostile bullets fired during this tick
+	grid       grid
+	density    float64 // the fraction of a bullet that the difficulty adds up
+	lastHit    uint64  // the tick a player was last hit on
+
+	// Events are what happened during the last tick
+	Events []Event
+}
+
+// NewWorld returns a world where the first player has joined, with the
+// ship in the middle of the screen
+func NewWorld() *World {
+	w := &World{
+		Physics:    DefaultPhysics,
+		Difficulty: DefaultDifficulty,
+		Intensity:  1,
+		Bullets:    make([]Bullet, 0, MAX_BULLETS),
+		Background: color.RGBA{0, 0, 0, 0xff},
+	}
+	w.join(0)
+	w.Players[0].Ship = Vec2{(W - SHIP_W) / 2, (H - SHIP_H) / 2}
+	return w
+}
+
+// SetSeed starts the random numbers from seed
+func (w *World) SetSeed(seed uint64) {
+	w.Rand.State, w.Seed = seed, seed
+}
+
+// GameOver returns true when no player is in the game
+func (w *World) GameOver() bool {
+	for i := range w.Players {
+		if w.Players[i].Joined {
+			return false
+		}
+	}
+	return true
+}
+
+// Step advances the world by one tick. There is one input per player,
+// missing inputs are treated as no buttons being held.
+func (w *World) Step(inputs []Input) {
+	clear(w.Events)
+	w.Events = w.Events[:0]
+
+	for i := range w.Players {
+		var in Input
+		if i < len(inputs) {
+			in = inputs[i]
+		}
+		w.stepPlayer(i, in)
+	}
+
+	w.calm()
+	w.runLevel()
+
+	w.grid.build(w.Enemies)
+	w.Bullets = w.updateBullets(w.Bullets)
+	w.Missiles = w.updateBullets(w.Missiles)
+
+	w.updateEnemies()
+	w.updateBoss()
+	w.updatePickups()
+	w.updateBlast()
+
+	w.Bullets = append(w.Bullets, w.fired...)
+	w.fired = w.fired[:0]
+
+	for i := range w.Players {
+		p := &w.Players[i]
+		for j := range w.Enemies {
+			e := &w.Enemies[j]
+			if p.hitbox(e.X, e.Y, ENEMY_W, ENEMY_H) {
+				w.hurt(i)
+			}
+		}
+	}
+
+	w.Tick++
+}
+
+// updateBullets moves the bullets and returns the ones that are still alive.
+// The bullets are compacted in place, so that the pool is never reallocated.
+func (w *World) updateBullets(bullets []Bullet) []Bullet {
+	aliveBullets := bullets[:0]
+	for i := 0; i < len(bullets); i++ {
+		w.moveBullet(&bullets[i])
+		if bullets[i].Life > 1 && !bullets[i].gone() && !w.hit(&bullets[i]) {
+			bullets[i].Life--
+			aliveBullets = append(aliveBullets, bullets[i])
+		}
+	}
+	// let go of the pattern runners of the bullets that were removed
+	clear(bullets[len(aliveBullets):])
+	return aliveBullets
+}
+
+// overlaps returns true if the two rectangles overlap
+func overlaps(ax, ay, aw, ah, bx, by, bw, bh float64) bool {
+	return ax < bx+bw && bx < ax+aw && ay < by+bh && by < ay+ah
+}
@@ -0,0 +1,53 @@
+package sim
+
+import (
+	"hi/pattern"
+)
+
+// Clone returns a deep copy of the world, for snapshots that can be rolled
+// back to. Kinds, paths and patterns are shared, since they never change.
+func (w *World) Clone() *World {
+	c := *w
+	c.Bullets = cloneBullets(w.Bullets)
+	c.Missiles = cloneBullets(w.Missiles)
+	c.fired = cloneBullets(w.fired)
+	c.grid = grid{}
+	c.Events = append([]Event(nil), w.Events...)
+	c.Enemies = append([]Enemy(nil), w.Enemies...)
+	for i := range c.Enemies {
+		c.Enemies[i].Action = cloneRunner(c.Enemies[i].Action)
+	}
+	if w.Boss != nil {
+		b := *w.Boss
+		b.Parts = append([]Part(nil), b.Parts...)
+		b.Attacks = make([]*pattern.Runner, len(w.Boss.Attacks))
+		for i, r := range w.Boss.Attacks {
+			b.Attacks[i] = cloneRunner(r)
+		}
+		c.Boss = &b
+	}
+	c.Pickups = append([]Pickup(nil), w.Pickups...)
+	if w.Blast != nil {
+		b := *w.Blast
+		c.Blast = &b
+	}
+	return &c
+}
+
+// cloneBullets copies bullets with the same capacity, so that the copy of
+// the pool is never reallocated either
+func cloneBullets(bullets []Bullet) []Bullet {
+	c := make([]Bullet, len(bullets), cap(bullets))
+	copy(c, bullets)
+	for i := range c {
+		c[i].Action = cloneRunner(c[i].Action)
+	}
+	return c
+}
+
+func cloneRunner(r *pattern.Runner) *pattern.Runner {
+	if r == nil {
+		return nil
+	}
+	return r.Clone()
+}
@@ -0,0 +1,38 @@
+package sim
+
+import (
+	"testing"
+)
+
+// TestClone checks that a clone plays out like the world it was made from,
+// without sharing anything with it, and keeps the bullet pool
+func TestClone(t *testing.T) {
+	w := NewWorld()
+	w.SetSeed(5)
+	for range 30 {
+		w.Step([]Input{Fire | Right})
+	}
+	c := w.Clone()
+	if c.Hash() != w.Hash() {
+		t.Fatalf("the clone has the hash %x, want %x", c.Hash(), w.Hash())
+	}
+	if cap(c.Bullets) != MAX_BULLETS {
+		t.Errorf("the bullets of the clone have a capacity of %d, want %d", cap(c.Bullets), MAX_BULLETS)
+	}
+	pool := &c.Bullets[:1][0]
+	for range 200 {
+		w.Step([]Input{Fire | Left})
+		c.Step([]Input{Fire | Left})
+	}
+	if c.Hash() != w.Hash() {
+		t.Errorf("after 200 ticks, the clone has the hash %x, want %x", c.Hash(), w.Hash())
+	}
+	if &c.Bullets[:1][0] != pool {
+		t.Error("the bullet pool of the clone was reallocated")
+	}
+	c.Bullets[0].X++
+	c.Players[0].Score++
+	if c.Hash() == w.Hash() {
+		t.Error("changing the clone changed the world")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"hi/achievements"
+	"hi/sim"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// ACHIEVEMENTS is the file with the achievements
+const ACHIEVEMENTS = "data/achievements.txt"
+
+// TOAST_TICKS is how long an unlocked achievement is shown
+const TOAST_TICKS = 3 * 60
+
+var (
+	// tracker counts the statistics, when a game is played with a window
+	tracker *achievements.Tracker
+
+	// cheated is set by cheats and by rewinding, and stops the statistics
+	// from being counted until the game is started again
+	cheated bool
+
+	// toasts are the achievements that were unlocked and are waiting to be shown
+	toasts     []string
+	toastTicks int
+)
+
+func init() {
+	bus.Subscribe(func(e sim.Event) {
+		if tracking() {
+			tracker.Handle(e)
+		}
+	})
+}
+
+// progressFile returns where the statistics and unlocked achievements are kept
+func progressFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hi", "progress.json"), nil
+}
+
+// loadAchievements starts counting the statistics of the players at this
+// computer. A broken progress file is reported, and the game starts from no
+// progress, but is not saved over.
+func loadAchievements() error {
+	list, err := achievements.Load(ACHIEVEMENTS)
+	if err != nil {
+		return err
+	}
+	filename, err := progressFile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	progress, err := achievements.LoadProgress(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+		return nil
+	}
+	tracker = &achievements.Tracker{Progress: progress, Achievements: list}
+	for i := range tracker.Local {
+		tracker.Local[i] = session == nil || i == session.Local
+	}
+	return nil
+}
+
+// tracking returns true if the statistics are counted. The demo, replays
+// and cheats do not count.
+func tracking() bool {
+	return tracker != nil && attract == nil && playback == nil && !cheated
+}
+
+// updateAchievements is called after every tick, and shows the achievements that were unlocked
+func updateAchievements() {
+	if !tracking() {
+		return
+	}
+	unlocked := tracker.Update(world)
+	for _, a := range unlocked {
+		toasts = append(toasts, achievementName(a))
+	}
+	if len(unlocked) > 0 {
+		saveProgress()
+	}
+}
+
+// saveProgress saves the statistics, and reports it if that fails
+func saveProgress() {
+	if tracker == nil {
+		return
+	}
+	filename, err := progressFile()
+	if err == nil {
+		err = tracker.Progress.Save(filename)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// updateToasts shows each unlocked achievement for a while, one after the other
+func updateToasts() {
+	if len(toasts) > 0 {
+		if toastTicks++; toastTicks > TOAST_TICKS {
+			toasts, toastTicks = toasts[1:], 0
+		}
+	}
+}
+
+// drawToasts shows the unlocked achievement at the top of the screen
+func drawToasts(screen *ebiten.Image) {
+	if len(toasts) == 0 {
+		return
+	}
+	text := msg("toast.achievement", toasts[0])
+	x := W/2 - textWidth(text)/2
+	vector.FillRect(screen, float32(x-4), 28, float32(textWidth(text)+8), 16, menuColor, false)
+	ebitenutil.DebugPrintAt(screen, text, x, 28)
+}
+
+// drawStats draws the lifetime statistics and the achievements, from the options menu
+func drawStats(screen *ebiten.Image) {
+	const x, lineH = 16, 12
+	vector.FillRect(screen, 0, 0, W, H, menuColor, false)
+	if tracker == nil {
+		ebitenutil.DebugPrintAt(screen, msg("stats.none"), x, 8)
+		return
+	}
+	p := tracker.Progress
+	s := p.Stats
+	count := func(id, stat string) string { return plural(id, int(s[stat])) }
+	lines := [][2]string{
+		{count("stats.games", "games"), msg("stats.time", s["seconds"]/3600, s["seconds"]/60%60)},
+		{plural("stats.shots", int(s["shots"]+s["missiles"])), msg("stats.accuracy", 100*p.Accuracy())},
+		{count("stats.kills", "kills"), count("stats.bosses", "bosses")},
+		{count("stats.deaths", "deaths"), count("stats.pickups", "pickups")},
+		{count("stats.bombs", "bombs"), msg("stats.best-score", s["best-score"])},
+	}
+	y := 8
+	ebitenutil.DebugPrintAt(screen, msg("stats.title"), x, y)
+	for _, line := range lines {
+		y += lineH
+		ebitenutil.DebugPrintAt(screen, line[0], x, y)
+		ebitenutil.DebugPrintAt(screen, line[1], W/2, y)
+	}
+	y += 2 * lineH
+	ebitenutil.DebugPrintAt(screen, msg("stats.achievements", len(p.Unlocked), len(tracker.Achievements)), x, y)
+	for _, a := range tracker.Achievements {
+		y += lineH
+		check := "[ ]"
+		progress := fmt.Sprintf("%d/%d", min(s[a.Stat], a.Goal), a.Goal)
+		if _, ok := p.Unlocked[a.ID]; ok {
+			check, progress = "[x]", ""
+		}
+		ebitenutil.DebugPrintAt(screen, check+" "+achievementName(a), x, y)
+		ebitenutil.DebugPrintAt(screen, progress, W-x-textWidth(progress), y)
+	}
+}
+
+// achievementName returns the name of the achievement in the current
+// language, or the name from the achievements file if it is not translated
+func achievementName(a *achievements.Achievement) string {
+	if id := "achievement." + a.ID; lang.Has(id) {
+		return msg(id)
+	}
+	return a.Name
+}
@@ -0,0 +1,16 @@
+package textfile
+
+import (
+	"errors"
+	"testing"
+)
+
+// Check is for the tests of the parsers. It fails the test unless err is an
+// *Error for the given line of filename, with the given message.
+func Check(t testing.TB, err error, filename string, line int, msg string) {
+	t.Helper()
+	var e *Error
+	if !errors.As(err, &e) || e.Filename != filename || e.Line != line || e.Msg != msg {
+		t.Errorf("got %v, want %s:%d: %s", err, filename, line, msg)
+	}
+}
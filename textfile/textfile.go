@@ -0,0 +1,22 @@
+// Package textfile has what the plain text data files of the game share,
+// like levels, bullet patterns and drop tables: errors that point at the
+// line where the problem is, and a check of them for the tests.
+package textfile
+
+import "fmt"
+
+// Error is a parse or validation error, with the line it was found on
+type Error struct {
+	Filename string
+	Line     int
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Msg)
+}
+
+// Errorf returns an *Error for the given line of a file
+func Errorf(filename string, line int, format string, args ...any) error {
+	return &Error{filename, line, fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,58 @@
+package textfile
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorf(t *testing.T) {
+	err := Errorf("levels/1.lvl", 12, "unknown enemy %q", "grunt")
+	if err.Error() != `levels/1.lvl:12: unknown enemy "grunt"` {
+		t.Errorf("the error is %q", err)
+	}
+	var e *Error
+	if !errors.As(fmt.Errorf("loading: %w", err), &e) || e.Line != 12 {
+		t.Errorf("a wrapped error is not an *Error: %v", e)
+	}
+}
+
+// recorder is a test that records its errors instead of failing
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheck(t *testing.T) {
+	err := fmt.Errorf("loading: %w", Errorf("a.txt", 3, "bad"))
+	for _, tt := range []struct {
+		err      error
+		filename string
+		line     int
+		msg      string
+		want     string
+	}{
+		{err, "a.txt", 3, "bad", ""},
+		{err, "b.txt", 3, "bad", "got loading: a.txt:3: bad, want b.txt:3: bad"},
+		{err, "a.txt", 4, "bad", "got loading: a.txt:3: bad, want a.txt:4: bad"},
+		{err, "a.txt", 3, "worse", "got loading: a.txt:3: bad, want a.txt:3: worse"},
+		{errors.New("a.txt:3: bad"), "a.txt", 3, "bad", "got a.txt:3: bad, want a.txt:3: bad"},
+		{nil, "a.txt", 3, "bad", "got <nil>, want a.txt:3: bad"},
+	} {
+		r := &recorder{}
+		Check(r, tt.err, tt.filename, tt.line, tt.msg)
+		got := ""
+		if len(r.errors) > 0 {
+			got = r.errors[0]
+		}
+		if got != tt.want || len(r.errors) > 1 {
+			t.Errorf("Check(%v, %s, %d, %s) reported %q, want %q", tt.err, tt.filename, tt.line, tt.msg, r.errors, tt.want)
+		}
+	}
+}